@@ -1,18 +1,73 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/quadgod/pgm/pkg/pgm"
 	"github.com/quadgod/pgm/pkg/pgm/cli"
+	"github.com/quadgod/pgm/pkg/pgm/lint"
+	"github.com/quadgod/pgm/pkg/pgm/report"
 	"golang.org/x/exp/slog"
 )
 
+// redactAttr скрывает пароли строк подключения в сообщениях и атрибутах лога
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, pgm.RedactSecrets(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, pgm.RedactSecrets(err.Error()))
+		}
+	}
+	return a
+}
+
+// isTerminal проверяет, подключен ли файл к терминалу
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmRevert показывает список откатываемых миграций и запрашивает подтверждение в терминале
+func confirmRevert(names []string) bool {
+	fmt.Fprintln(os.Stderr, "the following migrations will be reverted:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+	fmt.Fprint(os.Stderr, "revert? [y/N]: ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// newLogger создает логгер, пишущий в stderr: текстовый в терминале и json в остальных случаях
+func newLogger(flags *pgm.Flags) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(flags.EffectiveLogLevel())); err != nil {
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+
+	if isTerminal(os.Stderr) {
+		return slog.New(slog.NewTextHandler(os.Stderr, opts))
+	}
+
+	return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+}
+
 func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	flags := new(pgm.Flags)
 
 	showVersion := flag.Bool("version", false, "show version and exit")
@@ -23,9 +78,71 @@ func main() {
 	flag.StringVar(&flags.MigrationName, "migrationName", "", "migration name")
 	flag.StringVar(&flags.ConnectionString, "connectionString", os.Getenv("PG_CONNECTION_STRING"), "connection string")
 	flag.StringVar(&flags.Priority, "priority", string(pgm.FS), "db or fs migrations priority")
+	flag.StringVar(&flags.Output, "output", string(pgm.OUTPUT_TEXT), "output format: text, json or table")
+	flag.StringVar(&flags.Format, "format", string(pgm.PAIR), "format of created migration: pair or single")
+	flag.StringVar(&flags.FromUp, "fromUp", "", "file with up sql of created migration, down sql is generated from it")
+	flag.DurationVar(&flags.LockTimeout, "lockTimeout", 0, "max time to wait for migrations table lock, 0 waits forever")
+	flag.BoolVar(&flags.SplitStatements, "splitStatements", false, "execute migration sql statement by statement and report each statement")
+	flag.DurationVar(&flags.StatementTimeout, "statementTimeout", 0, "max execution time of migration sql or each statement with splitStatements, 0 disables the limit")
+	flag.BoolVar(&flags.Recursive, "recursive", false, "read migrations from subdirectories of migrations dir")
+	flag.StringVar(&flags.Tenants, "tenants", "", "comma separated tenant schemas to migrate")
+	flag.StringVar(&flags.TenantsQuery, "tenantsQuery", "", "sql query returning tenant schemas in the first column")
+	flag.StringVar(&flags.TenantsPattern, "tenantsPattern", "", "LIKE pattern of tenant schema names")
+	flag.IntVar(&flags.Parallel, "parallel", 1, "number of tenants or databases migrated concurrently")
+	flag.StringVar(&flags.OnError, "onError", string(pgm.STOP), "stop or continue migrating tenants or databases after a failure")
+	flag.BoolVar(&flags.FailFast, "failFast", false, "cancel running tenant or database migrations after the first failure")
+	flag.Func("connectionStrings", "connection string of a database to migrate, can be repeated", func(s string) error {
+		flags.ConnectionStrings = append(flags.ConnectionStrings, s)
+		return nil
+	})
+	flag.StringVar(&flags.ConnectionStringsFile, "connectionStringsFile", "", "file with connection strings of databases to migrate, one per line")
+	flag.BoolVar(&flags.Canary, "canary", false, "migrate the first database alone and the rest only if it succeeds")
+	flag.StringVar(&flags.Schemas, "schemas", "", "dump, diff: comma separated schemas to include, all user schemas by default")
+	flag.Func("schema", "dump, diff: schema to include, can be repeated", func(s string) error {
+		if flags.Schemas != "" {
+			flags.Schemas += ","
+		}
+		flags.Schemas += s
+		return nil
+	})
+	flag.StringVar(&flags.SchemaFile, "schemaFile", "schema.sql", "dump: schema snapshot file")
+	flag.BoolVar(&flags.Check, "check", false, "dump: compare the schema with the snapshot file instead of writing it")
+	flag.StringVar(&flags.Source, "source", "", "diff: connection string of the source database")
+	flag.StringVar(&flags.Target, "target", "", "diff: connection string of the target database")
+	flag.StringVar(&flags.Before, "before", "", "squash: migration before which all migrations are squashed into a baseline")
+	flag.StringVar(&flags.ArchiveDir, "archiveDir", "", "squash: directory to move squashed migrations to instead of deleting them")
+	flag.Func("lintRule", "lint: rule severity in rule=severity format, severity is error, warning or off, can be repeated", func(s string) error {
+		flags.LintRules = append(flags.LintRules, s)
+		return nil
+	})
+	flag.BoolVar(&flags.Pending, "pending", false, "lint: check only migrations not applied to the database from connectionString")
+	flag.BoolVar(&flags.Protected, "protected", false, "treat the database as protected: refuse to revert migrations without allowRevert")
+	flag.BoolVar(&flags.AllowRevert, "allowRevert", false, "allow reverting migrations of a protected database without confirmation")
+	flag.IntVar(&flags.MaxReverts, "maxReverts", 0, "max number of migrations reverted in one run, 0 disables the limit")
+	flag.Int64Var(&flags.MaxLostRows, "maxLostRows", 0, "max estimated number of rows destroyed by reverted migrations, 0 only reports the estimate")
+	flag.BoolVar(&flags.ForceDataLoss, "forceDataLoss", false, "revert migrations regardless of the estimated data loss")
+	flag.BoolVar(&flags.BackupOnRevert, "backupOnRevert", false, "copy tables changed by reverted migrations into a backup schema before reverting")
+	flag.DurationVar(&flags.OlderThan, "olderThan", 0, "purge: remove backups older than the duration, 0 removes all backups")
+	flags.Vars = pgm.VarsFromEnv(os.Environ())
+	flag.Func("var", "placeholder value in key=value format, can be repeated. overrides PGM_VAR_<key> env", func(s string) error {
+		key, value, err := pgm.ParseVar(s)
+		if err != nil {
+			return err
+		}
+		flags.Vars[key] = value
+		return nil
+	})
+
+	flag.StringVar(&flags.LogLevel, "logLevel", "info", "log level: debug, info, warn or error")
+	flag.BoolVar(&flags.Quiet, "quiet", false, "log only errors")
+	flag.BoolVar(&flags.Verbose, "verbose", false, "log debug messages")
+	flag.BoolVar(&flags.EchoSql, "echoSql", false, "log each sql statement with its migration name before it is executed")
 
 	flag.Parse()
 
+	logger := newLogger(flags)
+	slog.SetDefault(logger)
+
 	if *showVersion {
 		fmt.Printf("pgm v%s\n", pgm.Version)
 		os.Exit(0)
@@ -34,42 +151,152 @@ func main() {
 
 	if err := flags.Validate(); err != nil {
 		logger.Error("arguments validation error", "error", err)
-		os.Exit(1)
+		os.Exit(pgm.EXIT_VALIDATION)
 		return
 	}
 
 	opts := flags.ToMigratorOptions()
+	// подтверждение запрашивается только для одной базы данных, когда ввод и вывод подключены к терминалу
+	if !opts.IsTenantMode() && !opts.IsFanOutMode() && isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		opts.ConfirmRevert = confirmRevert
+	}
+	ctx := context.Background()
+	rep := report.New(opts.Command)
+
+	var runErr error
+	fail := func(err error) {
+		runErr = err
+		rep.Fail(err)
+	}
 
 	switch opts.Command {
 	case pgm.CREATE:
 		mig, err := cli.CreateMigrationFile(&opts)
-
 		if err != nil {
-			logger.Error("create migration error", "error", err)
-			os.Exit(1)
-			return
+			fail(fmt.Errorf("create migration error: %w", err))
+			break
 		}
 
-		logger.Info("migration files created", "up", mig.Up, "down", mig.Down)
+		if mig.Format == pgm.SINGLE {
+			rep.AddFiles(mig.Up)
+		} else {
+			rep.AddFiles(mig.Up, mig.Down)
+		}
 	case pgm.MIGRATE:
-		res, err := cli.Migrate(context.Background(), &opts)
+		switch {
+		case opts.IsTenantMode():
+			res, err := cli.MigrateTenants(ctx, &opts)
+			if err != nil {
+				fail(err)
+				break
+			}
+			rep.AddTargets(res)
+			runErr = pgm.CheckTargets(res)
+		case opts.IsFanOutMode():
+			res, err := cli.MigrateDatabases(ctx, &opts)
+			if err != nil {
+				fail(err)
+				break
+			}
+			rep.AddTargets(res)
+			runErr = pgm.CheckTargets(res)
+		default:
+			if opts.Output == pgm.OUTPUT_JSON {
+				opts.OnPlan = rep.SetPlan
+			}
+
+			res, err := cli.Migrate(ctx, &opts)
+			if err != nil {
+				fail(err)
+				break
+			}
+			rep.AddResults(res...)
+		}
+	case pgm.DOWN:
+		res, err := cli.Down(ctx, &opts)
 		if err != nil {
-			logger.Error("error occurs during migrate command execution", "error", err)
-			os.Exit(1)
-			return
+			fail(err)
+			break
+		}
+		rep.AddResults(*res)
+	case pgm.DUMP:
+		if err := cli.Dump(ctx, &opts); err != nil {
+			fail(err)
+			break
 		}
 
-		for _, r := range res {
-			logger.Info(r.MigrationName, "status", r.Status)
+		if !opts.Check {
+			rep.AddFiles(opts.SchemaFile)
 		}
-	case pgm.DOWN:
-		res, err := cli.Down(context.Background(), &opts)
+	case pgm.VERIFY:
+		res, err := cli.Verify(ctx, &opts)
+		rep.AddResults(res...)
+		if err != nil {
+			fail(err)
+		}
+	case pgm.SQUASH:
+		baseline, err := cli.Squash(ctx, &opts)
+		if err != nil {
+			fail(fmt.Errorf("squash migrations error: %w", err))
+			break
+		}
+
+		if baseline.Format == pgm.SINGLE {
+			rep.AddFiles(baseline.Up)
+		} else {
+			rep.AddFiles(baseline.Up, baseline.Down)
+		}
+	case pgm.PROTECT, pgm.UNPROTECT:
+		if err := cli.Protect(ctx, &opts, opts.Command == pgm.PROTECT); err != nil {
+			fail(err)
+		}
+	case pgm.BACKUPS:
+		backups, err := cli.Backups(ctx, &opts)
+		if err != nil {
+			fail(err)
+			break
+		}
+		rep.AddBackups(backups)
+	case pgm.PURGE:
+		backups, err := cli.PurgeBackups(ctx, &opts)
 		if err != nil {
-			logger.Error("error occurs during down command execution", "error", err)
-			os.Exit(1)
-			return
+			fail(err)
+			break
 		}
+		rep.AddBackups(backups)
+	case pgm.LINT:
+		findings, err := cli.Lint(ctx, &opts)
+		if err != nil {
+			fail(err)
+			break
+		}
+
+		rep.AddFindings(findings)
+		if errors, warnings := lint.Count(findings); errors > 0 {
+			fail(&pgm.LintError{Errors: errors, Warnings: warnings})
+		}
+	case pgm.DIFF:
+		changes, err := cli.Diff(ctx, &opts)
+		if err != nil {
+			fail(err)
+			break
+		}
+
+		rep.AddDiff(changes)
+		if len(changes) > 0 {
+			fail(&pgm.SchemaDiffError{Differences: len(changes)})
+		}
+	}
+
+	rep.Finish()
+
+	if err := report.Write(os.Stdout, opts.Output, rep); err != nil {
+		logger.Error("write report error", "error", err)
+		os.Exit(pgm.EXIT_ERROR)
+		return
+	}
 
-		logger.Info(res.MigrationName, "status", res.Status)
+	if runErr != nil {
+		os.Exit(pgm.ExitCode(runErr))
 	}
 }
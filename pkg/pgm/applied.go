@@ -0,0 +1,32 @@
+package pgm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AppliedMigration запись о примененной миграции из таблицы миграций
+type AppliedMigration struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	DownSql   string    `json:"downSql"`
+	Checksum  string    `json:"checksum,omitempty"`
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+// IsGo возвращает true, если запись относится к go миграции
+func (m AppliedMigration) IsGo() bool {
+	return m.Kind == string(GO)
+}
+
+// IsRepeatable возвращает true, если запись относится к повторяемой миграции
+func (m AppliedMigration) IsRepeatable() bool {
+	return m.Kind == string(REPEATABLE)
+}
+
+// Checksum вычисляет контрольную сумму sql миграции
+func Checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,16 @@
+package pgm
+
+import "time"
+
+// Backup резервная копия таблицы, сделанная перед откатом миграции
+type Backup struct {
+	// Schema схема резервных копий запуска, Table копия таблицы в ней
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// Source скопированная таблица со схемой
+	Source string `json:"source"`
+	// Migration миграция, перед откатом которой сделана копия
+	Migration string    `json:"migration"`
+	Rows      int64     `json:"rows"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,236 @@
+// Package catalog описывает объекты схемы базы данных, прочитанные из системного каталога postgres,
+// и формирует из них детерминированный sql снимок схемы
+package catalog
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kind тип объекта схемы
+type Kind string
+
+const (
+	EXTENSION  Kind = "extension"
+	SCHEMA     Kind = "schema"
+	TYPE       Kind = "type"
+	SEQUENCE   Kind = "sequence"
+	TABLE      Kind = "table"
+	COLUMN     Kind = "column"
+	CONSTRAINT Kind = "constraint"
+	INDEX      Kind = "index"
+	FUNCTION   Kind = "function"
+	VIEW       Kind = "view"
+	TRIGGER    Kind = "trigger"
+	GRANT      Kind = "grant"
+)
+
+// Kinds типы объектов в порядке их следования в снимке схемы
+var Kinds = []Kind{SCHEMA, EXTENSION, TYPE, SEQUENCE, TABLE, COLUMN, CONSTRAINT, INDEX, FUNCTION, VIEW, TRIGGER, GRANT}
+
+// ForeignKey тип ограничения внешнего ключа в Attributes["type"]
+const ForeignKey = "f"
+
+// Object объект схемы
+type Object struct {
+	Kind Kind `json:"kind"`
+	// Parent таблица столбца, ограничения, индекса или триггера, объект привилегии
+	Parent string `json:"parent,omitempty"`
+	// Name имя объекта, квалифицированное схемой для объектов верхнего уровня
+	Name string `json:"name"`
+	// Definition sql определение объекта
+	Definition string `json:"definition"`
+	// Attributes свойства объекта, которые сравниваются по отдельности, например тип и значение по умолчанию столбца
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Key уникальный ключ объекта в каталоге
+func (o Object) Key() string {
+	if o.Parent == "" {
+		return string(o.Kind) + " " + o.Name
+	}
+	return string(o.Kind) + " " + o.Parent + " " + o.Name
+}
+
+// Catalog объекты схемы базы данных
+type Catalog struct {
+	Objects []Object `json:"objects"`
+}
+
+func kindOrder(kind Kind) int {
+	for i, k := range Kinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(Kinds)
+}
+
+// less порядок объектов в каталоге: по типу, родителю и имени. Столбцы внутри таблицы не упорядочиваются.
+func less(a Object, b Object) bool {
+	if a.Kind != b.Kind {
+		return kindOrder(a.Kind) < kindOrder(b.Kind)
+	}
+	if a.Parent != b.Parent {
+		return a.Parent < b.Parent
+	}
+	if a.Kind == COLUMN {
+		return false
+	}
+	return a.Name < b.Name
+}
+
+// New создает каталог и упорядочивает объекты по типу, родителю и имени.
+// Столбцы сохраняют исходный порядок внутри таблицы.
+func New(objects []Object) *Catalog {
+	sorted := append(make([]Object, 0, len(objects)), objects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+
+	return &Catalog{Objects: sorted}
+}
+
+// Exclude возвращает каталог без таблиц names и их столбцов, ограничений, индексов, триггеров и привилегий
+func (c *Catalog) Exclude(names ...string) *Catalog {
+	excluded := func(o Object) bool {
+		for _, name := range names {
+			if o.Name == name || o.Parent == name || strings.HasSuffix(o.Parent, " "+name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	objects := make([]Object, 0, len(c.Objects))
+	for _, o := range c.Objects {
+		if o.Kind == SCHEMA || o.Kind == EXTENSION || !excluded(o) {
+			objects = append(objects, o)
+		}
+	}
+
+	return &Catalog{Objects: objects}
+}
+
+// Column создает столбец таблицы table.
+// identity - a или d для столбцов GENERATED ALWAYS / BY DEFAULT AS IDENTITY,
+// generated - s для вычисляемых столбцов, выражение которых передается в defaultExpr.
+func Column(table string, name string, dataType string, notNull bool, defaultExpr string, identity string, generated string) Object {
+	def := name + " " + dataType
+	attrs := map[string]string{"type": dataType, "notNull": "false"}
+
+	switch {
+	case generated == "s":
+		def += " GENERATED ALWAYS AS (" + defaultExpr + ") STORED"
+		attrs["generated"] = defaultExpr
+	case identity == "a":
+		def += " GENERATED ALWAYS AS IDENTITY"
+		attrs["identity"] = "always"
+	case identity == "d":
+		def += " GENERATED BY DEFAULT AS IDENTITY"
+		attrs["identity"] = "by default"
+	case defaultExpr != "":
+		def += " DEFAULT " + defaultExpr
+		attrs["default"] = defaultExpr
+	}
+
+	if notNull {
+		def += " NOT NULL"
+		attrs["notNull"] = "true"
+	}
+
+	return Object{Kind: COLUMN, Parent: table, Name: name, Definition: def, Attributes: attrs}
+}
+
+// statement завершает sql определение точкой с запятой
+func statement(def string) string {
+	return strings.TrimRight(strings.TrimSpace(def), ";") + ";"
+}
+
+// tableSql формирует CREATE TABLE со столбцами таблицы
+func tableSql(table Object, columns []Object) string {
+	if strings.HasPrefix(table.Definition, "PARTITION OF") {
+		return "CREATE TABLE " + table.Name + " " + table.Definition + ";"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE " + table.Name + " (")
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n    " + column.Definition)
+	}
+	if len(columns) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(")")
+
+	if table.Definition != "" {
+		sb.WriteString(" " + table.Definition)
+	}
+
+	return sb.String() + ";"
+}
+
+// snapshotHeader первая строка снимка схемы
+const snapshotHeader = "-- schema snapshot generated by pgm, do not edit"
+
+// DDL формирует sql создания объектов каталога. Объекты следуют в порядке создания,
+// поэтому sql можно выполнить на пустой базе данных, но зависимости между функциями и представлениями не учитываются.
+func (c *Catalog) DDL() string {
+	columns := make(map[string][]Object)
+	for _, o := range c.Objects {
+		if o.Kind == COLUMN {
+			columns[o.Parent] = append(columns[o.Parent], o)
+		}
+	}
+
+	blocks := make([]string, 0, len(c.Objects))
+	// внешние ключи добавляются после остальных ограничений, чтобы ссылаться на уже созданные ключи
+	foreignKeys := make([]string, 0)
+	flushForeignKeys := func() {
+		blocks = append(blocks, foreignKeys...)
+		foreignKeys = foreignKeys[:0]
+	}
+
+	for _, o := range c.Objects {
+		if kindOrder(o.Kind) > kindOrder(CONSTRAINT) {
+			flushForeignKeys()
+		}
+
+		switch o.Kind {
+		case COLUMN:
+			continue
+		case TABLE:
+			blocks = append(blocks, tableSql(o, columns[o.Name]))
+		case CONSTRAINT:
+			def := "ALTER TABLE " + o.Parent + " ADD CONSTRAINT " + o.Name + " " + o.Definition + ";"
+			if o.Attributes["type"] == ForeignKey {
+				foreignKeys = append(foreignKeys, def)
+			} else {
+				blocks = append(blocks, def)
+			}
+		default:
+			blocks = append(blocks, statement(o.Definition))
+		}
+	}
+	flushForeignKeys()
+
+	if len(blocks) == 0 {
+		return ""
+	}
+
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// SQL формирует снимок схемы с заголовком.
+// Снимок предназначен для ревью и сравнения с другими снимками.
+func (c *Catalog) SQL() string {
+	ddl := c.DDL()
+	if ddl == "" {
+		return snapshotHeader + "\n"
+	}
+
+	return snapshotHeader + "\n\n" + ddl
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New(t *testing.T) {
+	c := New([]Object{
+		{Kind: INDEX, Parent: "public.users", Name: "users_name", Definition: "CREATE INDEX users_name ON public.users USING btree (name)"},
+		Column("public.users", "name", "text", false, "", "", ""),
+		{Kind: TABLE, Name: "public.users"},
+		Column("public.users", "id", "bigint", true, "", "a", ""),
+		{Kind: TABLE, Name: "public.accounts"},
+		{Kind: SCHEMA, Name: "billing", Definition: "CREATE SCHEMA billing"},
+	})
+
+	keys := make([]string, 0)
+	for _, o := range c.Objects {
+		keys = append(keys, o.Key())
+	}
+
+	assert.Equal(t, []string{
+		"schema billing",
+		"table public.accounts",
+		"table public.users",
+		"column public.users name",
+		"column public.users id",
+		"index public.users users_name",
+	}, keys)
+}
+
+func Test_Column(t *testing.T) {
+	id := Column("public.users", "id", "bigint", true, "", "a", "")
+	assert.Equal(t, "id bigint GENERATED ALWAYS AS IDENTITY NOT NULL", id.Definition)
+	assert.Equal(t, "always", id.Attributes["identity"])
+
+	status := Column("public.users", "status", "text", false, "'new'::text", "", "")
+	assert.Equal(t, "status text DEFAULT 'new'::text", status.Definition)
+	assert.Equal(t, "false", status.Attributes["notNull"])
+
+	total := Column("public.orders", "total", "numeric", false, "(price * qty)", "", "s")
+	assert.Equal(t, "total numeric GENERATED ALWAYS AS ((price * qty)) STORED", total.Definition)
+}
+
+func Test_SQL(t *testing.T) {
+	c := New([]Object{
+		{Kind: TABLE, Name: "public.orders"},
+		Column("public.orders", "id", "bigint", true, "", "", ""),
+		Column("public.orders", "user_id", "bigint", false, "", "", ""),
+		{Kind: TABLE, Name: "public.users"},
+		Column("public.users", "id", "bigint", true, "", "", ""),
+		{Kind: TABLE, Name: "public.orders_2025", Definition: "PARTITION OF public.orders FOR VALUES IN (2025)"},
+		{Kind: CONSTRAINT, Parent: "public.orders", Name: "orders_user_fk", Definition: "FOREIGN KEY (user_id) REFERENCES public.users(id)", Attributes: map[string]string{"type": ForeignKey}},
+		{Kind: CONSTRAINT, Parent: "public.users", Name: "users_pkey", Definition: "PRIMARY KEY (id)", Attributes: map[string]string{"type": "p"}},
+		{Kind: GRANT, Parent: "TABLE public.users", Name: "reader", Definition: "GRANT SELECT ON TABLE public.users TO reader"},
+		{Kind: TABLE, Name: "public.migrations"},
+		Column("public.migrations", "migration_name", "text", true, "", "", ""),
+	}).Exclude("public.migrations")
+
+	assert.Equal(t, `-- schema snapshot generated by pgm, do not edit
+
+CREATE TABLE public.orders (
+    id bigint NOT NULL,
+    user_id bigint
+);
+
+CREATE TABLE public.orders_2025 PARTITION OF public.orders FOR VALUES IN (2025);
+
+CREATE TABLE public.users (
+    id bigint NOT NULL
+);
+
+ALTER TABLE public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+GRANT SELECT ON TABLE public.users TO reader;
+`, c.SQL())
+}
@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"sort"
+)
+
+// ChangeType тип различия объекта между исходной и целевой схемой
+type ChangeType string
+
+const (
+	// ADDED объект есть только в целевой схеме
+	ADDED ChangeType = "added"
+	// REMOVED объект есть только в исходной схеме
+	REMOVED ChangeType = "removed"
+	// CHANGED определение объекта различается
+	CHANGED ChangeType = "changed"
+)
+
+// AttributeChange различие отдельного свойства объекта, например типа столбца
+type AttributeChange struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Change различие объекта схемы
+type Change struct {
+	Type   ChangeType `json:"type"`
+	Kind   Kind       `json:"kind"`
+	Parent string     `json:"parent,omitempty"`
+	Name   string     `json:"name"`
+	// Source и Target определения объекта в исходной и целевой схеме
+	Source string `json:"source,omitempty"`
+	Target string `json:"target,omitempty"`
+	// Attributes различающиеся свойства измененного объекта
+	Attributes []AttributeChange `json:"attributes,omitempty"`
+}
+
+func (c Change) object() Object {
+	return Object{Kind: c.Kind, Parent: c.Parent, Name: c.Name}
+}
+
+// attributeChanges возвращает различающиеся свойства объекта в порядке имен свойств
+func attributeChanges(source Object, target Object) []AttributeChange {
+	names := make([]string, 0)
+	for name := range source.Attributes {
+		names = append(names, name)
+	}
+	for name := range target.Attributes {
+		if _, ok := source.Attributes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]AttributeChange, 0)
+	for _, name := range names {
+		if source.Attributes[name] != target.Attributes[name] {
+			changes = append(changes, AttributeChange{Name: name, Source: source.Attributes[name], Target: target.Attributes[name]})
+		}
+	}
+	return changes
+}
+
+// isPartOf объект принадлежит таблице table: столбец, ограничение, индекс, триггер или привилегия таблицы
+func isPartOf(o Object, table string) bool {
+	return o.Parent == table || o.Parent == "TABLE "+table
+}
+
+// Diff сравнивает исходный и целевой каталоги. Объекты добавленных и удаленных таблиц
+// не перечисляются отдельно, различия упорядочены так же, как объекты каталога.
+func Diff(source *Catalog, target *Catalog) []Change {
+	targetObjects := make(map[string]Object, len(target.Objects))
+	for _, o := range target.Objects {
+		targetObjects[o.Key()] = o
+	}
+
+	sourceKeys := make(map[string]bool, len(source.Objects))
+	changes := make([]Change, 0)
+	for _, s := range source.Objects {
+		sourceKeys[s.Key()] = true
+
+		t, ok := targetObjects[s.Key()]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: REMOVED, Kind: s.Kind, Parent: s.Parent, Name: s.Name, Source: s.Definition})
+		case s.Definition != t.Definition:
+			changes = append(changes, Change{
+				Type:       CHANGED,
+				Kind:       s.Kind,
+				Parent:     s.Parent,
+				Name:       s.Name,
+				Source:     s.Definition,
+				Target:     t.Definition,
+				Attributes: attributeChanges(s, t),
+			})
+		}
+	}
+
+	for _, t := range target.Objects {
+		if !sourceKeys[t.Key()] {
+			changes = append(changes, Change{Type: ADDED, Kind: t.Kind, Parent: t.Parent, Name: t.Name, Target: t.Definition})
+		}
+	}
+
+	tables := make(map[string]ChangeType)
+	for _, c := range changes {
+		if c.Kind == TABLE && c.Type != CHANGED {
+			tables[c.Name] = c.Type
+		}
+	}
+
+	result := make([]Change, 0, len(changes))
+	for _, c := range changes {
+		skip := false
+		for table, changeType := range tables {
+			if c.Type == changeType && isPartOf(c.object(), table) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			result = append(result, c)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return less(result[i].object(), result[j].object())
+	})
+
+	return result
+}
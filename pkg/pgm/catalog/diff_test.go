@@ -0,0 +1,50 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Diff(t *testing.T) {
+	source := New([]Object{
+		{Kind: TABLE, Name: "public.users"},
+		Column("public.users", "id", "bigint", true, "", "", ""),
+		Column("public.users", "email", "text", false, "", "", ""),
+		{Kind: TABLE, Name: "public.legacy"},
+		Column("public.legacy", "id", "bigint", true, "", "", ""),
+		{Kind: INDEX, Parent: "public.users", Name: "users_email", Definition: "CREATE INDEX users_email ON public.users USING btree (email)"},
+		{Kind: FUNCTION, Name: "public.now_utc()", Definition: "CREATE OR REPLACE FUNCTION public.now_utc() ..."},
+	})
+	target := New([]Object{
+		{Kind: TABLE, Name: "public.users"},
+		Column("public.users", "id", "bigint", true, "", "", ""),
+		Column("public.users", "email", "character varying(255)", true, "", "", ""),
+		{Kind: TABLE, Name: "public.audit"},
+		Column("public.audit", "id", "bigint", true, "", "", ""),
+		{Kind: GRANT, Parent: "TABLE public.audit", Name: "reader", Definition: "GRANT SELECT ON TABLE public.audit TO reader"},
+		{Kind: FUNCTION, Name: "public.now_utc()", Definition: "CREATE OR REPLACE FUNCTION public.now_utc() ..."},
+	})
+
+	changes := Diff(source, target)
+
+	assert.Equal(t, []Change{
+		{Type: ADDED, Kind: TABLE, Name: "public.audit", Target: ""},
+		{Type: REMOVED, Kind: TABLE, Name: "public.legacy", Source: ""},
+		{
+			Type:   CHANGED,
+			Kind:   COLUMN,
+			Parent: "public.users",
+			Name:   "email",
+			Source: "email text",
+			Target: "email character varying(255) NOT NULL",
+			Attributes: []AttributeChange{
+				{Name: "notNull", Source: "false", Target: "true"},
+				{Name: "type", Source: "text", Target: "character varying(255)"},
+			},
+		},
+		{Type: REMOVED, Kind: INDEX, Parent: "public.users", Name: "users_email", Source: "CREATE INDEX users_email ON public.users USING btree (email)"},
+	}, changes)
+
+	assert.Empty(t, Diff(source, source))
+}
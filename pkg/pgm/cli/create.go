@@ -1,16 +1,57 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/ddl"
 	"github.com/quadgod/pgm/pkg/pgm/fs"
+	"golang.org/x/exp/slog"
 )
 
 func CreateMigrationFile(opts *pgm.MigratorOptions) (*pgm.Migration, error) {
-	migrations, err := fs.CreateMigration(opts.MigrationsDir, opts.MigrationName)
+	var upSql string
+	var down *ddl.Result
+	if opts.FromUp != "" {
+		content, err := os.ReadFile(opts.FromUp)
+		if err != nil {
+			return nil, err
+		}
+
+		upSql = string(content)
+		if down, err = ddl.GenerateDown(upSql); err != nil {
+			return nil, fmt.Errorf("generate down sql for \"%s\": %w", opts.FromUp, err)
+		}
+	}
+
+	create := fs.CreateMigration
+	if opts.Format == pgm.SINGLE {
+		create = fs.CreateSingleFileMigration
+	}
+
+	migrations, err := create(opts.MigrationsDir, opts.MigrationName)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if down == nil {
+		return migrations, nil
+	}
+
+	if err = fs.WriteMigrationSql(migrations, upSql, down.SQL); err != nil {
+		return nil, err
+	}
+
+	if down.Todos > 0 {
+		slog.Warn(
+			"generated down sql needs manual changes",
+			"file", migrations.Down,
+			"todos", down.Todos,
+			"marker", ddl.TodoMarker,
+		)
+	}
+
 	return migrations, nil
 }
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+// readConnectionStrings читает строки подключения из файла, по одной на строку.
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+func readConnectionStrings(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read connection strings file error: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	connectionStrings := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		connectionStrings = append(connectionStrings, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read connection strings file error: %w", err)
+	}
+
+	return connectionStrings, nil
+}
+
+// migrateDatabase применяет миграции в базу данных по строке подключения
+func migrateDatabase(ctx context.Context, opts *pgm.MigratorOptions, connectionString string) ([]pgm.MigrationResult, error) {
+	dbOpts := *opts
+	dbOpts.ConnectionString = connectionString
+	dbOpts.ConnectionStrings = nil
+	dbOpts.ConnectionStringsFile = ""
+
+	return Migrate(ctx, &dbOpts)
+}
+
+// runCanary мигрирует первую цель отдельно и остальные только после ее успешной миграции
+func runCanary(
+	ctx context.Context,
+	targets []string,
+	parallel int,
+	policy pgm.ErrorPolicy,
+	run runFunc,
+) []pgm.TargetResult {
+	results := runParallel(ctx, targets[:1], 1, policy, run)
+
+	if results[0].Status != pgm.TARGET_SUCCEEDED {
+		for _, target := range targets[1:] {
+			results = append(results, pgm.TargetResult{Target: target, Status: pgm.TARGET_SKIPPED})
+		}
+		return results
+	}
+
+	return append(results, runParallel(ctx, targets[1:], parallel, policy, run)...)
+}
+
+// MigrateDatabases применяет миграции в каждую базу данных из списка строк подключения и файла.
+// Одновременно мигрируется не более opts.Parallel баз данных, в режиме canary первая база данных
+// мигрируется отдельно. Цели в результатах указываются строками подключения со скрытым паролем.
+func MigrateDatabases(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.TargetResult, error) {
+	connectionStrings := append(make([]string, 0), opts.ConnectionStrings...)
+
+	if opts.ConnectionStringsFile != "" {
+		fromFile, err := readConnectionStrings(opts.ConnectionStringsFile)
+		if err != nil {
+			return nil, err
+		}
+		connectionStrings = append(connectionStrings, fromFile...)
+	}
+
+	if len(connectionStrings) == 0 {
+		return nil, errors.New("connection strings not found")
+	}
+
+	run := func(ctx context.Context, connectionString string) ([]pgm.MigrationResult, error) {
+		return migrateDatabase(ctx, opts, connectionString)
+	}
+
+	var results []pgm.TargetResult
+	if opts.Canary {
+		results = runCanary(ctx, connectionStrings, opts.Parallel, opts.OnError, run)
+	} else {
+		results = runParallel(ctx, connectionStrings, opts.Parallel, opts.OnError, run)
+	}
+
+	for i := range results {
+		results[i].Target = pgm.RedactConnectionString(results[i].Target)
+	}
+
+	return results, nil
+}
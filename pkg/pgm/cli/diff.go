@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+	"golang.org/x/exp/slog"
+)
+
+// readDatabaseCatalog подключается к существующей базе данных и читает ее схему
+func readDatabaseCatalog(ctx context.Context, connectionString string, opts *pgm.MigratorOptions) (*catalog.Catalog, error) {
+	slog.Debug("reading database schema", "connectionString", pgm.RedactConnectionString(connectionString))
+
+	pool, err := db.ConnectExisting(ctx, connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("database connection errors: %w", err)
+	}
+	defer pool.Close()
+
+	return readCatalog(ctx, pool, opts)
+}
+
+// Diff сравнивает схемы баз данных opts.Source и opts.Target независимо от их таблиц миграций
+func Diff(ctx context.Context, opts *pgm.MigratorOptions) ([]catalog.Change, error) {
+	source, err := readDatabaseCatalog(ctx, opts.Source, opts)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	target, err := readDatabaseCatalog(ctx, opts.Target, opts)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+
+	return catalog.Diff(source, target), nil
+}
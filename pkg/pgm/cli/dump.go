@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+)
+
+// readCatalog читает схемы opts.Schemas базы данных без таблицы миграций
+func readCatalog(ctx context.Context, conn db.Conn, opts *pgm.MigratorOptions) (*catalog.Catalog, error) {
+	cat, err := db.ReadCatalog(ctx, conn, opts.Schemas)
+	if err != nil {
+		return nil, fmt.Errorf("read schema error: %w", err)
+	}
+
+	migrationsTableSchema := opts.MigrationsTableSchema
+	if migrationsTableSchema == "" {
+		migrationsTableSchema = "public"
+	}
+
+	migTbl := fmt.Sprintf("%s.%s", migrationsTableSchema, opts.MigrationsTable)
+	return cat.Exclude(migTbl, migTbl+db.BackupsTableSuffix), nil
+}
+
+// Dump читает схему базы данных и записывает ее снимок в opts.SchemaFile.
+// С opts.Check снимок не записывается, а сверяется с файлом: при расхождении возвращается *pgm.SchemaDriftError.
+func Dump(ctx context.Context, opts *pgm.MigratorOptions) error {
+	pool, err := connect(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("database connection errors: %w", err)
+	}
+	defer pool.Close()
+
+	cat, err := readCatalog(ctx, pool, opts)
+	if err != nil {
+		return err
+	}
+	snapshot := cat.SQL()
+
+	if !opts.Check {
+		return os.WriteFile(opts.SchemaFile, []byte(snapshot), 0644)
+	}
+
+	committed, err := os.ReadFile(opts.SchemaFile)
+	if err != nil {
+		return fmt.Errorf("read schema snapshot error: %w", err)
+	}
+
+	return compareSnapshot(opts.SchemaFile, string(committed), snapshot)
+}
+
+// compareSnapshot сравнивает сохраненный снимок схемы с актуальным построчно
+func compareSnapshot(file string, committed string, actual string) error {
+	expectedLines := strings.Split(strings.ReplaceAll(committed, "\r\n", "\n"), "\n")
+	actualLines := strings.Split(actual, "\n")
+
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var expected, got string
+		if i < len(expectedLines) {
+			expected = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+
+		if expected != got || i >= len(expectedLines) || i >= len(actualLines) {
+			return &pgm.SchemaDriftError{File: file, Line: i + 1, Expected: expected, Actual: got}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compareSnapshot(t *testing.T) {
+	snapshot := "-- header\n\nCREATE TABLE public.users (\n    id integer\n);\n"
+
+	t.Run("should accept equal snapshots with different line endings", func(t *testing.T) {
+		committed := "-- header\r\n\r\nCREATE TABLE public.users (\r\n    id integer\r\n);\r\n"
+		assert.Nil(t, compareSnapshot("schema.sql", committed, snapshot))
+	})
+
+	t.Run("should report the first different line", func(t *testing.T) {
+		actual := "-- header\n\nCREATE TABLE public.users (\n    id bigint\n);\n"
+		err := compareSnapshot("schema.sql", snapshot, actual)
+
+		var driftErr *pgm.SchemaDriftError
+		if assert.True(t, errors.As(err, &driftErr)) {
+			assert.Equal(t, 4, driftErr.Line)
+			assert.Equal(t, "    id integer", driftErr.Expected)
+			assert.Equal(t, "    id bigint", driftErr.Actual)
+		}
+		assert.True(t, errors.Is(err, pgm.ErrSchemaDrift))
+	})
+
+	t.Run("should report lines missing in the committed snapshot", func(t *testing.T) {
+		actual := snapshot + "\nCREATE INDEX users_id ON public.users USING btree (id);\n"
+		err := compareSnapshot("schema.sql", snapshot, actual)
+
+		assert.EqualError(t, err, "schema snapshot schema.sql doesn't match the database at line 7\n"+
+			"  - <end of file>\n"+
+			"  + CREATE INDEX users_id ON public.users USING btree (id);")
+	})
+}
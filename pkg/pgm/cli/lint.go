@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/fs"
+	"github.com/quadgod/pgm/pkg/pgm/lint"
+)
+
+// pendingMigrations возвращает имена миграций, которые применил бы migrate
+func pendingMigrations(ctx context.Context, opts *pgm.MigratorOptions) (map[string]bool, error) {
+	steps, err := Plan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if step.Action == pgm.APPLY {
+			pending[step.Name] = true
+		}
+	}
+
+	return pending, nil
+}
+
+// Lint проверяет sql миграций и повторяемых миграций правилами lint.Rules с важностью из opts.LintRules.
+// С opts.Pending проверяются только миграции, которые еще не применены к базе данных.
+func Lint(ctx context.Context, opts *pgm.MigratorOptions) ([]lint.Finding, error) {
+	rules, err := lint.Configure(lint.Rules(), opts.LintRules)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := fs.ReadSource(opts.MigrationsDir, fs.ReadOptions{Recursive: opts.Recursive})
+	if err != nil {
+		return nil, err
+	}
+
+	var pending map[string]bool
+	if opts.Pending {
+		if pending, err = pendingMigrations(ctx, opts); err != nil {
+			return nil, fmt.Errorf("read pending migrations error: %w", err)
+		}
+	}
+
+	findings := make([]lint.Finding, 0)
+	for _, migration := range append(source.Migrations, source.Repeatables...) {
+		if pending != nil && !pending[migration.Name] {
+			continue
+		}
+
+		migrationFindings, err := lint.Lint(migration, rules)
+		if err != nil {
+			return nil, err
+		}
+
+		findings = append(findings, migrationFindings...)
+	}
+
+	return findings, nil
+}
@@ -2,218 +2,145 @@ package cli
 
 import (
 	"context"
-	"errors"
-	"fmt"
-	"os"
+	iofs "io/fs"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/quadgod/pgm/pkg/pgm"
 	"github.com/quadgod/pgm/pkg/pgm/db"
-	"github.com/quadgod/pgm/pkg/pgm/fs"
+	"github.com/quadgod/pgm/pkg/pgm/migrator"
+	"golang.org/x/exp/slog"
 )
 
-func applyWithDBPriority(
-	ctx context.Context,
-	tx pgx.Tx,
-	migTbl string,
-	fsMigrations []pgm.Migration,
-	dbMigrations []pgm.Migration,
-) ([]pgm.MigrationResult, error) {
-	appliedMigrations := make([]pgm.MigrationResult, 0)
-
-	for i, fsMigration := range fsMigrations {
-		isSameDbMigrationExist := len(dbMigrations) > 0 && len(dbMigrations) > i
-
-		// Если миграция в базе существует и она аналогична миграции
-		// из файловой системы, то переходим к следующей миграции.
-		if isSameDbMigrationExist && dbMigrations[i].Name == fsMigration.Name {
-			continue
-		}
-
-		// Если миграция в базе не идентична миграции
-		// в файловой системе, то возвращаем ошибку.
-		if isSameDbMigrationExist && dbMigrations[i].Name != fsMigration.Name {
-			return nil, fmt.Errorf(
-				"migrations are not the same in file system and db. %s != %s",
-				dbMigrations[i].Name,
-				fsMigration.Name,
-			)
-		}
-
-		if !isSameDbMigrationExist {
-			upSqlBytes, err := os.ReadFile(fsMigration.Up)
-			if err != nil {
-				return nil, err
-			}
-
-			downSqlBytes, err := os.ReadFile(fsMigration.Down)
-			if err != nil {
-				return nil, err
-			}
-
-			err = db.ApplyMigration(
-				ctx,
-				tx,
-				fsMigration.Name,
-				migTbl,
-				string(upSqlBytes),
-				string(downSqlBytes),
-			)
-
-			if err != nil {
-				return nil, err
-			}
-
-			appliedMigrations = append(appliedMigrations, pgm.MigrationResult{
-				MigrationName: fsMigration.Name,
-				Status:        pgm.APPLIED,
-			})
-		}
+// connect подключается к базе данных. С opts.EchoSql каждый запрос выводится в лог перед выполнением.
+func connect(ctx context.Context, opts *pgm.MigratorOptions) (*pgxpool.Pool, error) {
+	slog.Debug("connecting to database", "connectionString", pgm.RedactConnectionString(opts.ConnectionString))
+
+	connectOpts := make([]db.ConnectOption, 0)
+	if opts.EchoSql {
+		connectOpts = append(connectOpts, db.WithQueryTracer(&db.EchoTracer{
+			Echo: func(migrationName string, sql string) {
+				slog.Info("sql", "migration", migrationName, "sql", sql)
+			},
+		}))
 	}
 
-	return appliedMigrations, nil
+	return db.Connect(ctx, opts.ConnectionString, connectOpts...)
 }
 
-func applyWithFSPriority(
-	ctx context.Context,
-	tx pgx.Tx,
-	migTbl string,
-	fsMigrations []pgm.Migration,
-	dbMigrations []pgm.Migration,
-) ([]pgm.MigrationResult, error) {
-	results := make([]pgm.MigrationResult, 0)
-
-	if len(fsMigrations) == 0 {
-		revertedNames, err := db.Reset(ctx, tx, migTbl)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, name := range revertedNames {
-			results = append(results, pgm.MigrationResult{
-				MigrationName: name,
-				Status:        pgm.REVERTED,
-			})
-		}
-
-		return results, nil
+// newMigrator создает мигратор с параметрами командной строки
+func newMigrator(conn db.Conn, opts *pgm.MigratorOptions, extra ...migrator.Option) (*migrator.Migrator, error) {
+	options := append(
+		migrator.FromOptions(opts),
+		migrator.WithWarningHandler(func(warning string) {
+			slog.Warn("migrations dir warning", "warning", warning)
+		}),
+	)
+	options = append(options, extra...)
+
+	return migrator.New(conn, options...)
+}
+
+func Migrate(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.MigrationResult, error) {
+	pool, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
+	defer pool.Close()
 
-	for i, dbMigration := range dbMigrations {
-		isFSMigrationSameIndexExist := len(fsMigrations) > 0 && len(fsMigrations) > i
-
-		// Если миграция в базе существует и она аналогична миграции
-		// из файловой системы, то переходим к следующей миграции.
-		if isFSMigrationSameIndexExist && fsMigrations[i].Name == dbMigration.Name {
-			continue
-		}
-
-		// Если миграция в базе не идентична миграции
-		// в файловой системе, то откатываем до состояния идентичности.
-		if (isFSMigrationSameIndexExist && fsMigrations[i].Name != dbMigration.Name) || !isFSMigrationSameIndexExist {
-			migNamesToRevert := make([]string, 0)
-			for _, dbMig := range dbMigrations[i:] {
-				migNamesToRevert = append(migNamesToRevert, dbMig.Name)
-			}
-			revertResults, err := db.RevertMigrations(ctx, tx, migTbl, migNamesToRevert)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, revertResults...)
-			break
-		}
+	m, err := newMigrator(pool, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	dbMigrationsAfterRevert, err := db.GetMigrations(ctx, tx, migTbl)
+	return m.Migrate(ctx)
+}
+
+// Plan возвращает шаги, которые выполнит Migrate, не изменяя базу данных
+func Plan(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.PlanStep, error) {
+	pool, err := connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	defer pool.Close()
 
-	appliedMigrations, err := applyWithDBPriority(ctx, tx, migTbl, fsMigrations, dbMigrationsAfterRevert)
+	m, err := newMigrator(pool, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	results = append(results, appliedMigrations...)
+	return m.Plan(ctx)
+}
+
+// MigrateFS применяет миграции из файловой системы fsys (например, embed.FS).
+// Позволяет сервису применять вшитые в бинарный файл миграции при старте.
+func MigrateFS(ctx context.Context, fsys iofs.FS, opts *pgm.MigratorOptions) ([]pgm.MigrationResult, error) {
+	fsOpts := *opts
+	fsOpts.MigrationsFS = fsys
 
-	return results, nil
+	return Migrate(ctx, &fsOpts)
 }
 
-func Migrate(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.MigrationResult, error) {
-	fsMigrations, err := fs.ReadMigrationsDir(opts.MigrationsDir)
+// Verify проверяет, что откат каждой миграции возвращает схему к состоянию до ее применения.
+// Изменения базы данных откатываются.
+func Verify(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.MigrationResult, error) {
+	pool, err := connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	defer pool.Close()
 
-	pool, err := db.Connect(ctx, opts.ConnectionString)
+	m, err := newMigrator(pool, opts)
 	if err != nil {
 		return nil, err
 	}
+
+	return m.Verify(ctx)
+}
+
+// Protect отмечает базу данных как защищенную от отката миграций или снимает отметку
+func Protect(ctx context.Context, opts *pgm.MigratorOptions, protected bool) error {
+	pool, err := connect(ctx, opts)
+	if err != nil {
+		return err
+	}
 	defer pool.Close()
 
-	err = db.EnsureMigrationsTable(ctx, pool, opts.MigrationsTableSchema, opts.MigrationsTable)
+	m, err := newMigrator(pool, opts)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	return m.Protect(ctx, protected)
+}
+
+// Backups возвращает резервные копии таблиц, сделанные перед откатом миграций
+func Backups(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.Backup, error) {
+	pool, err := connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		rollbackErr := tx.Rollback(ctx)
-		if !errors.Is(rollbackErr, pgx.ErrTxClosed) {
-			err = errors.Join(err, rollbackErr)
-		}
-	}()
-
-	err = db.LockMigrationsTable(ctx, tx, opts.MigrationsTableNameWithSchema())
+	defer pool.Close()
+
+	m, err := newMigrator(pool, opts)
 	if err != nil {
-		return nil, fmt.Errorf("lock migrations table error: %w", err)
+		return nil, err
 	}
 
-	dbMigrations, err := db.GetMigrations(ctx, tx, opts.MigrationsTableNameWithSchema())
+	return m.Backups(ctx)
+}
+
+// PurgeBackups удаляет резервные копии таблиц старше opts.OlderThan и возвращает удаленные копии
+func PurgeBackups(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.Backup, error) {
+	pool, err := connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	defer pool.Close()
 
-	switch opts.Priority {
-	case pgm.DB:
-		result, err := applyWithDBPriority(
-			ctx,
-			tx,
-			opts.MigrationsTableNameWithSchema(),
-			fsMigrations,
-			dbMigrations,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if err = tx.Commit(ctx); err != nil {
-			return nil, err
-		}
-
-		return result, nil
-	case pgm.FS:
-		result, err := applyWithFSPriority(
-			ctx,
-			tx,
-			opts.MigrationsTableNameWithSchema(),
-			fsMigrations,
-			dbMigrations,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if err = tx.Commit(ctx); err != nil {
-			return nil, err
-		}
-
-		return result, nil
-	default:
-		err = fmt.Errorf("unknown priority %s", opts.Priority)
+	m, err := newMigrator(pool, opts)
+	if err != nil {
 		return nil, err
 	}
+
+	return m.PurgeBackups(ctx, opts.OlderThan)
 }
@@ -9,9 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/quadgod/pgm/pkg/pgm"
 	"github.com/quadgod/pgm/pkg/pgm/db"
 	"github.com/quadgod/pgm/pkg/pgm/fs"
+	"github.com/quadgod/pgm/pkg/pgm/migrator"
 	"github.com/stretchr/testify/assert"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -243,4 +245,415 @@ func Test_Migrate(t *testing.T) {
 			t.FailNow()
 		}
 	})
+
+	t.Run("should plan, migrate and revert through migrator with existing pool", func(t *testing.T) {
+		pool, err := db.Connect(ctx, connStr)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer pool.Close()
+
+		_, err = genMigration(migrationsDir, "first_migration", "table1")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		var executed []pgm.PlanStep
+		m, err := migrator.New(
+			pool,
+			migrator.WithDir(migrationsDir),
+			migrator.WithPriority(pgm.DB),
+			migrator.WithMigrationsTable("detmir_jobs", "migrations"),
+			migrator.WithLockTimeout(time.Second),
+			migrator.WithPlanHandler(func(steps []pgm.PlanStep) { executed = steps }),
+		)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		steps, err := m.Plan(ctx)
+		if assert.Nil(t, err) && assert.Len(t, steps, 1) {
+			assert.Equal(t, pgm.APPLY, steps[0].Action)
+		}
+
+		results, err := m.Migrate(ctx)
+		if assert.Nil(t, err) && assert.Len(t, results, 1) {
+			assert.Equal(t, pgm.APPLIED, results[0].Status)
+			assert.NotEmpty(t, results[0].Checksum)
+		}
+		assert.Equal(t, steps, executed)
+
+		statuses, err := m.Status(ctx)
+		if assert.Nil(t, err) && assert.Len(t, statuses, 1) {
+			assert.Equal(t, pgm.STATE_APPLIED, statuses[0].State)
+			assert.False(t, statuses[0].Modified)
+		}
+
+		result, err := m.Down(ctx)
+		if assert.Nil(t, err) {
+			assert.Equal(t, pgm.REVERTED, result.Status)
+		}
+
+		_, err = m.Down(ctx)
+		assert.ErrorIs(t, err, pgm.ErrNoMigrations)
+
+		err = os.RemoveAll(migrationsDir)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+	})
+
+	t.Run("should verify migrations and report incomplete down", func(t *testing.T) {
+		opts := new(pgm.MigratorOptions)
+		opts.ConnectionString = connStr
+		opts.Command = pgm.VERIFY
+		opts.Priority = pgm.DB
+		opts.MigrationsTableSchema = "detmir_jobs"
+		opts.MigrationsTable = "migrations"
+		opts.MigrationsDir = migrationsDir
+
+		reversible, err := fs.CreateMigration(migrationsDir, "first_reversible")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		err = fs.WriteMigrationSql(
+			reversible,
+			"CREATE TABLE public.verify_a (id INT);\nCREATE INDEX verify_a_id ON public.verify_a (id);",
+			"DROP TABLE public.verify_a;",
+		)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		incomplete, err := fs.CreateMigration(migrationsDir, "second_incomplete")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		err = fs.WriteMigrationSql(
+			incomplete,
+			"CREATE TABLE public.verify_b (id INT);\nCREATE SEQUENCE IF NOT EXISTS public.verify_b_seq;",
+			"DROP TABLE public.verify_b;",
+		)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		results, err := Verify(ctx, opts)
+		assert.ErrorIs(t, err, pgm.ErrSchemaDrift)
+		if assert.Len(t, results, 2) {
+			assert.Equal(t, pgm.VERIFIED, results[0].Status)
+			assert.Equal(t, pgm.INCOMPLETE, results[1].Status)
+			if assert.Len(t, results[1].Changes, 1) {
+				assert.Equal(t, "public.verify_b_seq", results[1].Changes[0].Name)
+			}
+		}
+
+		pool, err := db.Connect(ctx, connStr)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer pool.Close()
+
+		// проверка откатывает все изменения
+		publicTables, err := db.Tables(ctx, pool, "public")
+		if assert.Nil(t, err) {
+			assert.Len(t, publicTables, 0)
+		}
+
+		err = os.RemoveAll(migrationsDir)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+	})
+
+	t.Run("should refuse to revert migrations of protected database", func(t *testing.T) {
+		pool, err := db.Connect(ctx, connStr)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer pool.Close()
+
+		_, err = genMigration(migrationsDir, "first_migration", "table1")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		newMigrator := func(opts ...migrator.Option) *migrator.Migrator {
+			m, err := migrator.New(pool, append([]migrator.Option{
+				migrator.WithDir(migrationsDir),
+				migrator.WithPriority(pgm.FS),
+				migrator.WithMigrationsTable("detmir_jobs", "migrations"),
+			}, opts...)...)
+			if !assert.Nil(t, err) {
+				t.FailNow()
+			}
+			return m
+		}
+
+		_, err = newMigrator().Migrate(ctx)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		if !assert.Nil(t, newMigrator().Protect(ctx, true)) {
+			t.FailNow()
+		}
+
+		if !assert.Nil(t, os.RemoveAll(migrationsDir)) {
+			t.FailNow()
+		}
+
+		_, err = newMigrator().Migrate(ctx)
+		assert.ErrorIs(t, err, pgm.ErrRevertRefused)
+
+		_, err = newMigrator().Down(ctx)
+		assert.ErrorIs(t, err, pgm.ErrRevertRefused)
+
+		results, err := newMigrator(migrator.WithAllowRevert(true)).Migrate(ctx)
+		if assert.Nil(t, err) && assert.Len(t, results, 1) {
+			assert.Equal(t, pgm.REVERTED, results[0].Status)
+		}
+
+		assert.Nil(t, newMigrator().Protect(ctx, false))
+	})
+
+	t.Run("should backup tables before revert and purge backups", func(t *testing.T) {
+		pool, err := db.Connect(ctx, connStr)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer pool.Close()
+
+		_, err = genMigration(migrationsDir, "backup_migration", "backup_table")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		m, err := migrator.New(pool,
+			migrator.WithDir(migrationsDir),
+			migrator.WithPriority(pgm.FS),
+			migrator.WithMigrationsTable("detmir_jobs", "migrations"),
+			migrator.WithBackupOnRevert(true),
+		)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		if _, err = m.Migrate(ctx); !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		if _, err = pool.Exec(ctx, "INSERT INTO test.backup_table (active) VALUES (true), (false)"); !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		if !assert.Nil(t, os.RemoveAll(migrationsDir)) {
+			t.FailNow()
+		}
+
+		results, err := m.Migrate(ctx)
+		if !assert.Nil(t, err) || !assert.Len(t, results, 1) || !assert.Len(t, results[0].Backups, 1) {
+			t.FailNow()
+		}
+		backup := results[0].Backups[0]
+		assert.Equal(t, "test.backup_table", backup.Source)
+		assert.Equal(t, int64(2), backup.Rows)
+
+		var rows int64
+		err = pool.QueryRow(ctx, fmt.Sprintf(`SELECT count(*) FROM %s.%s`, backup.Schema, backup.Table)).Scan(&rows)
+		if assert.Nil(t, err) {
+			assert.Equal(t, int64(2), rows)
+		}
+
+		backups, err := m.Backups(ctx)
+		if assert.Nil(t, err) && assert.Len(t, backups, 1) {
+			assert.Equal(t, backup.Table, backups[0].Table)
+		}
+
+		purged, err := m.PurgeBackups(ctx, time.Hour)
+		if assert.Nil(t, err) {
+			assert.Len(t, purged, 0)
+		}
+
+		purged, err = m.PurgeBackups(ctx, 0)
+		if assert.Nil(t, err) {
+			assert.Len(t, purged, 1)
+		}
+
+		backups, err = m.Backups(ctx)
+		if assert.Nil(t, err) {
+			assert.Len(t, backups, 0)
+		}
+	})
+
+	t.Run("should run callbacks around migrations and roll back on callback error", func(t *testing.T) {
+		pool, err := db.Connect(ctx, connStr)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer pool.Close()
+
+		first, err := genMigration(migrationsDir, "first_hooked", "hooked_table1")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		second, err := genMigration(migrationsDir, "second_hooked", "hooked_table2")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		callbacks := map[string]string{
+			"beforeMigrate.sql":    "CREATE TABLE IF NOT EXISTS public.hook_log (migration TEXT);",
+			"afterEachMigrate.sql": "INSERT INTO public.hook_log VALUES ('applied');",
+		}
+		for name, sql := range callbacks {
+			if err = os.WriteFile(path.Join(migrationsDir, name), []byte(sql), 0644); !assert.Nil(t, err) {
+				t.FailNow()
+			}
+		}
+
+		var calls []string
+		var logged []int
+		newMigrator := func(opts ...migrator.Option) *migrator.Migrator {
+			options := []migrator.Option{
+				migrator.WithDir(migrationsDir),
+				migrator.WithPriority(pgm.DB),
+				migrator.WithMigrationsTable("hooks_jobs", "migrations"),
+			}
+			for _, event := range pgm.HookEvents {
+				options = append(options, migrator.WithHook(event, func(ctx context.Context, tx pgx.Tx, info pgm.HookInfo) error {
+					calls = append(calls, fmt.Sprintf("%s %s", info.Event, info.MigrationName))
+					return nil
+				}))
+			}
+			// sql колбэк события выполняется раньше go колбэка того же события
+			options = append(options, migrator.WithHook(pgm.AFTER_EACH_MIGRATE, func(ctx context.Context, tx pgx.Tx, info pgm.HookInfo) error {
+				var count int
+				if err := tx.QueryRow(ctx, "SELECT count(*) FROM public.hook_log").Scan(&count); err != nil {
+					return err
+				}
+				logged = append(logged, count)
+				return nil
+			}))
+
+			m, err := migrator.New(pool, append(options, opts...)...)
+			if !assert.Nil(t, err) {
+				t.FailNow()
+			}
+			return m
+		}
+
+		_, err = newMigrator().Migrate(ctx)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []string{
+			"beforeMigrate ",
+			"beforeEachMigrate " + first.Name,
+			"afterEachMigrate " + first.Name,
+			"beforeEachMigrate " + second.Name,
+			"afterEachMigrate " + second.Name,
+			"afterMigrate ",
+		}, calls)
+		assert.Equal(t, []int{1, 2}, logged)
+
+		calls = nil
+		_, err = newMigrator().Down(ctx)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []string{
+			"beforeRevert ",
+			"beforeEachRevert " + second.Name,
+			"afterEachRevert " + second.Name,
+			"afterRevert ",
+		}, calls)
+
+		failing := migrator.WithHook(pgm.AFTER_EACH_MIGRATE, func(ctx context.Context, tx pgx.Tx, info pgm.HookInfo) error {
+			return errors.New("callback failed")
+		})
+		_, err = newMigrator(failing).Migrate(ctx)
+
+		var hookErr *pgm.HookError
+		if assert.ErrorAs(t, err, &hookErr) {
+			assert.Equal(t, pgm.AFTER_EACH_MIGRATE, hookErr.Event)
+			assert.Equal(t, second.Name, hookErr.MigrationName)
+		}
+		assert.Equal(t, pgm.EXIT_MIGRATION, pgm.ExitCode(err))
+
+		// миграция и запись sql колбэка откатываются вместе с транзакцией
+		var firstExists, secondExists bool
+		err = pool.QueryRow(
+			ctx,
+			"SELECT to_regclass('test.hooked_table1') IS NOT NULL, to_regclass('test.hooked_table2') IS NOT NULL",
+		).Scan(&firstExists, &secondExists)
+		if assert.Nil(t, err) {
+			assert.True(t, firstExists)
+			assert.False(t, secondExists)
+		}
+
+		var count int
+		if err = pool.QueryRow(ctx, "SELECT count(*) FROM public.hook_log").Scan(&count); assert.Nil(t, err) {
+			assert.Equal(t, 2, count)
+		}
+
+		assert.Nil(t, db.ResetForTests(ctx, pool, "hooks_jobs.migrations"))
+		_, err = pool.Exec(ctx, "DROP TABLE public.hook_log")
+		assert.Nil(t, err)
+		assert.Nil(t, os.RemoveAll(migrationsDir))
+	})
+
+	t.Run("should read migrations table created before kind and checksum columns", func(t *testing.T) {
+		pool, err := db.Connect(ctx, connStr)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer pool.Close()
+
+		migration, err := genMigration(migrationsDir, "legacy_migration", "legacy_table")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		// таблица миграций в том виде, в котором ее создавали версии без столбцов kind и checksum
+		_, err = pool.Exec(ctx, fmt.Sprintf(`
+			CREATE SCHEMA legacy_jobs;
+			CREATE TABLE legacy_jobs.migrations (
+				migration_name VARCHAR(512) NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP(3),
+				down_sql TEXT NOT NULL,
+				CONSTRAINT "migrations_pk" PRIMARY KEY (migration_name)
+			);
+			INSERT INTO legacy_jobs.migrations (migration_name, down_sql) VALUES ('%s', '%s');`,
+			migration.Name,
+			genDownSql("legacy_table"),
+		))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		m, err := migrator.New(pool,
+			migrator.WithDir(migrationsDir),
+			migrator.WithPriority(pgm.DB),
+			migrator.WithMigrationsTable("legacy_jobs", "migrations"),
+		)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		statuses, err := m.Status(ctx)
+		if assert.Nil(t, err) && assert.Len(t, statuses, 1) {
+			assert.Equal(t, migration.Name, statuses[0].Name)
+			assert.Equal(t, pgm.STATE_APPLIED, statuses[0].State)
+			assert.Equal(t, "", statuses[0].AppliedChecksum)
+			assert.False(t, statuses[0].Modified)
+		}
+
+		steps, err := m.Plan(ctx)
+		if assert.Nil(t, err) {
+			assert.Len(t, steps, 0)
+		}
+
+		assert.Nil(t, os.RemoveAll(migrationsDir))
+	})
 }
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+// runFunc мигрирует одну цель
+type runFunc func(ctx context.Context, target string) ([]pgm.MigrationResult, error)
+
+// runParallel мигрирует цели, запуская не более parallel миграций одновременно.
+// При политике pgm.STOP после первой ошибки новые цели не запускаются и помечаются пропущенными,
+// уже запущенные миграции завершаются. При политике pgm.FAIL_FAST уже запущенные миграции
+// дополнительно прерываются отменой контекста. Результаты возвращаются в порядке targets.
+func runParallel(
+	ctx context.Context,
+	targets []string,
+	parallel int,
+	policy pgm.ErrorPolicy,
+	run runFunc,
+) []pgm.TargetResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]pgm.TargetResult, len(targets))
+	sem := make(chan struct{}, parallel)
+	var stopped atomic.Bool
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		sem <- struct{}{}
+
+		if stopped.Load() || ctx.Err() != nil {
+			<-sem
+			results[i] = pgm.TargetResult{Target: target, Status: pgm.TARGET_SKIPPED}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			started := time.Now()
+			migResults, err := run(ctx, target)
+
+			result := pgm.TargetResult{Target: target, Status: pgm.TARGET_SUCCEEDED, Results: migResults, Err: err}
+			result.Duration = time.Since(started)
+			if err != nil {
+				result.Status = pgm.TARGET_FAILED
+				switch policy {
+				case pgm.STOP:
+					stopped.Store(true)
+				case pgm.FAIL_FAST:
+					stopped.Store(true)
+					cancel()
+				}
+			}
+			results[i] = result
+		}(i, target)
+	}
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runParallel(t *testing.T) {
+	targets := []string{"t1", "t2", "t3", "t4"}
+
+	t.Run("should migrate all targets and keep their order", func(t *testing.T) {
+		var running, maxRunning atomic.Int32
+		results := runParallel(context.Background(), targets, 2, pgm.STOP, func(ctx context.Context, target string) ([]pgm.MigrationResult, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			return []pgm.MigrationResult{{MigrationName: target + "_m", Status: pgm.APPLIED}}, nil
+		})
+
+		if !assert.Len(t, results, 4) {
+			t.FailNow()
+		}
+		for i, r := range results {
+			assert.Equal(t, targets[i], r.Target)
+			assert.Equal(t, pgm.TARGET_SUCCEEDED, r.Status)
+			assert.Equal(t, targets[i]+"_m", r.Results[0].MigrationName)
+		}
+		assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+		assert.Nil(t, pgm.CheckTargets(results))
+	})
+
+	t.Run("should skip remaining targets after failure with stop policy", func(t *testing.T) {
+		results := runParallel(context.Background(), targets, 1, pgm.STOP, func(ctx context.Context, target string) ([]pgm.MigrationResult, error) {
+			if target == "t2" {
+				return nil, errors.New("boom")
+			}
+			return nil, nil
+		})
+
+		assert.Equal(t, pgm.TARGET_SUCCEEDED, results[0].Status)
+		assert.Equal(t, pgm.TARGET_FAILED, results[1].Status)
+		assert.EqualError(t, results[1].Err, "boom")
+		assert.Equal(t, pgm.TARGET_SKIPPED, results[2].Status)
+		assert.Equal(t, pgm.TARGET_SKIPPED, results[3].Status)
+		assert.EqualError(t, pgm.CheckTargets(results), "1 of 4 targets failed: t2, 2 skipped")
+	})
+
+	t.Run("should migrate remaining targets after failure with continue policy", func(t *testing.T) {
+		results := runParallel(context.Background(), targets, 1, pgm.CONTINUE, func(ctx context.Context, target string) ([]pgm.MigrationResult, error) {
+			if target == "t2" {
+				return nil, errors.New("boom")
+			}
+			return nil, nil
+		})
+
+		assert.Equal(t, pgm.TARGET_FAILED, results[1].Status)
+		assert.Equal(t, pgm.TARGET_SUCCEEDED, results[2].Status)
+		assert.Equal(t, pgm.TARGET_SUCCEEDED, results[3].Status)
+		assert.EqualError(t, pgm.CheckTargets(results), "1 of 4 targets failed: t2, 0 skipped")
+	})
+
+	t.Run("should cancel running targets after failure with fail fast policy", func(t *testing.T) {
+		failed := make(chan struct{})
+		results := runParallel(context.Background(), targets[:2], 2, pgm.FAIL_FAST, func(ctx context.Context, target string) ([]pgm.MigrationResult, error) {
+			if target == "t1" {
+				<-failed
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			close(failed)
+			return nil, errors.New("boom")
+		})
+
+		assert.Equal(t, pgm.TARGET_FAILED, results[0].Status)
+		assert.ErrorIs(t, results[0].Err, context.Canceled)
+		assert.Equal(t, pgm.TARGET_FAILED, results[1].Status)
+	})
+}
+
+func Test_runCanary(t *testing.T) {
+	targets := []string{"eu", "us", "asia"}
+
+	t.Run("should skip remaining targets when canary fails", func(t *testing.T) {
+		calls := make([]string, 0)
+		results := runCanary(context.Background(), targets, 2, pgm.CONTINUE, func(ctx context.Context, target string) ([]pgm.MigrationResult, error) {
+			calls = append(calls, target)
+			return nil, errors.New("boom")
+		})
+
+		assert.Equal(t, []string{"eu"}, calls)
+		assert.Equal(t, pgm.TARGET_FAILED, results[0].Status)
+		assert.Equal(t, pgm.TargetResult{Target: "us", Status: pgm.TARGET_SKIPPED}, results[1])
+		assert.Equal(t, pgm.TargetResult{Target: "asia", Status: pgm.TARGET_SKIPPED}, results[2])
+	})
+
+	t.Run("should migrate remaining targets after canary succeeds", func(t *testing.T) {
+		results := runCanary(context.Background(), targets, 2, pgm.STOP, func(ctx context.Context, target string) ([]pgm.MigrationResult, error) {
+			return nil, nil
+		})
+
+		if !assert.Len(t, results, 3) {
+			t.FailNow()
+		}
+		for i, r := range results {
+			assert.Equal(t, targets[i], r.Target)
+			assert.Equal(t, pgm.TARGET_SUCCEEDED, r.Status)
+		}
+	})
+}
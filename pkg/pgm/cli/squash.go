@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/ddl"
+	"github.com/quadgod/pgm/pkg/pgm/fs"
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+	"golang.org/x/exp/slog"
+)
+
+var dataStatementRegexp = regexp.MustCompile(`(?i)^(INSERT|UPDATE|DELETE|MERGE|TRUNCATE|COPY)\b`)
+
+// changesData проверяет, содержит ли sql запросы, изменяющие данные
+func changesData(sql string) bool {
+	statements, err := sqlsplit.Split(sql)
+	if err != nil {
+		return false
+	}
+
+	for _, st := range statements {
+		if st.Meta {
+			if strings.HasPrefix(strings.ToLower(st.SQL), `\copy`) {
+				return true
+			}
+			continue
+		}
+
+		if dataStatementRegexp.MatchString(sqlsplit.StripLeadingComments(st.SQL)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// baselineName возвращает имя базовой миграции с числовым префиксом последней объединенной миграции
+func baselineName(last string) string {
+	prefix, _, _ := strings.Cut(last, "_")
+	return prefix + "_baseline"
+}
+
+// baselineSql формирует up sql базовой миграции: список объединенных миграций и схему после их применения
+func baselineSql(before string, squashed []pgm.SquashedMigration, schema string) string {
+	return fmt.Sprintf("-- baseline generated by pgm squash from migrations before %s\n%s\n%s", before, fs.FormatSquashed(squashed), schema)
+}
+
+// Squash применяет миграции, предшествующие opts.Before, к пустой базе данных, записывает полученную схему
+// в новую базовую миграцию и удаляет объединенные миграции или переносит их в opts.ArchiveDir.
+// Изменения базы данных откатываются.
+func Squash(ctx context.Context, opts *pgm.MigratorOptions) (*pgm.Migration, error) {
+	pool, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+
+	m, err := newMigrator(pool, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cat, migrations, err := m.Replay(ctx, opts.Before)
+	if err != nil {
+		return nil, err
+	}
+
+	name := baselineName(migrations[len(migrations)-1].Name)
+	for _, migration := range migrations {
+		if migration.Name == name {
+			return nil, fmt.Errorf("baseline %s already exists", name)
+		}
+	}
+	if name >= opts.Before {
+		return nil, fmt.Errorf("baseline %s must be ordered before %s", name, opts.Before)
+	}
+
+	squashed := make([]pgm.SquashedMigration, 0, len(migrations))
+	for _, migration := range migrations {
+		squashed = append(squashed, pgm.Squash(migration))
+
+		upSql, _, err := fs.ReadMigrationSql(migration)
+		if err != nil {
+			return nil, err
+		}
+
+		if changesData(upSql) {
+			slog.Warn("squashed migration changes data, the baseline contains only the schema", "migration", migration.Name)
+		}
+	}
+
+	schema := cat.DDL()
+	down, err := ddl.GenerateDown(schema)
+	if err != nil {
+		return nil, fmt.Errorf("generate down sql for baseline %s: %w", name, err)
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = pgm.PAIR
+	}
+
+	baseline, err := fs.CreateBaselineMigration(opts.MigrationsDir, name, format, baselineSql(opts.Before, squashed, schema), down.SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	if down.Todos > 0 {
+		slog.Warn(
+			"generated down sql needs manual changes",
+			"file", baseline.Down,
+			"todos", down.Todos,
+			"marker", ddl.TodoMarker,
+		)
+	}
+
+	for _, migration := range migrations {
+		files, err := fs.RemoveMigration(migration, opts.ArchiveDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.ArchiveDir != "" {
+			slog.Info("squashed migration archived", "migration", migration.Name, "files", files, "archiveDir", opts.ArchiveDir)
+		} else {
+			slog.Info("squashed migration removed", "migration", migration.Name, "files", files)
+		}
+	}
+
+	return baseline, nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_changesData(t *testing.T) {
+	assert.False(t, changesData("CREATE TABLE users (id bigint);\n-- INSERT INTO users VALUES (1);"))
+	assert.True(t, changesData("CREATE TABLE users (id bigint);\nINSERT INTO users VALUES (1);"))
+	assert.True(t, changesData("/* seed */ update users set id = 2;"))
+	assert.True(t, changesData("\\copy users from 'users.csv'"))
+}
+
+func Test_baselineSql(t *testing.T) {
+	assert.Equal(t, "1700000000000_baseline", baselineName("1700000000000_add_orders"))
+
+	sql := baselineSql("1800000000000_add_index", []pgm.SquashedMigration{{Name: "1700000000000_add_orders"}}, "CREATE TABLE public.orders (\n    id bigint\n);\n")
+	assert.Equal(t, `-- baseline generated by pgm squash from migrations before 1800000000000_add_index
+-- pgm:squashed 1700000000000_add_orders
+
+CREATE TABLE public.orders (
+    id bigint
+);
+`, sql)
+}
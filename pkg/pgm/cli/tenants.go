@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+	"github.com/quadgod/pgm/pkg/pgm/migrator"
+	"golang.org/x/exp/slog"
+)
+
+// discoverTenants получает схемы тенантов из списка, sql запроса или шаблона имени
+func discoverTenants(ctx context.Context, conn db.Conn, opts *pgm.MigratorOptions) ([]string, error) {
+	switch {
+	case len(opts.Tenants) > 0:
+		return opts.Tenants, nil
+	case opts.TenantsQuery != "":
+		tenants, err := db.QuerySchemas(ctx, conn, opts.TenantsQuery)
+		if err != nil {
+			return nil, fmt.Errorf("tenants query error: %w", err)
+		}
+		return tenants, nil
+	default:
+		tenants, err := db.ListSchemas(ctx, conn, opts.TenantsPattern)
+		if err != nil {
+			return nil, fmt.Errorf("list tenant schemas error: %w", err)
+		}
+		return tenants, nil
+	}
+}
+
+// MigrateTenants применяет миграции в схему каждого тенанта.
+// Таблица миграций создается в схеме тенанта, миграции выполняются с search_path тенанта.
+// Ошибка возвращается, если не удалось подключиться или получить список тенантов,
+// ошибки отдельных тенантов возвращаются в результатах.
+func MigrateTenants(ctx context.Context, opts *pgm.MigratorOptions) ([]pgm.TargetResult, error) {
+	pool, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+
+	tenants, err := discoverTenants(ctx, pool, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("tenants not found")
+	}
+
+	slog.Info("tenants discovered", "count", len(tenants))
+
+	return runParallel(ctx, tenants, opts.Parallel, opts.OnError, func(ctx context.Context, tenant string) ([]pgm.MigrationResult, error) {
+		tenantOpts := *opts
+		tenantOpts.MigrationsTableSchema = tenant
+
+		m, err := newMigrator(pool, &tenantOpts, migrator.WithSearchPath(tenant))
+		if err != nil {
+			return nil, err
+		}
+
+		return m.Migrate(ctx)
+	}), nil
+}
@@ -0,0 +1,49 @@
+package pgm
+
+import "fmt"
+
+// DataLoss оценка данных, которые уничтожит откат миграции
+type DataLoss struct {
+	Migration string `json:"migration"`
+	Table     string `json:"table"`
+	// Column удаляемый столбец. Пустой, если уничтожаются все строки таблицы
+	Column string `json:"column,omitempty"`
+	// Line номер строки down sql, с которой начинается уничтожающий данные запрос
+	Line int `json:"line"`
+	// Rows и Bytes примерные количество строк и размер данных по статистике postgres
+	Rows  int64 `json:"rows"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Object возвращает тип и имя объекта, данные которого уничтожаются
+func (l DataLoss) Object() string {
+	if l.Column == "" {
+		return "table " + l.Table
+	}
+	return fmt.Sprintf("column %s.%s", l.Table, l.Column)
+}
+
+// TotalDataLoss суммирует оценки уничтожаемых данных
+func TotalDataLoss(losses []DataLoss) (rows int64, bytes int64) {
+	for _, l := range losses {
+		rows += l.Rows
+		bytes += l.Bytes
+	}
+	return rows, bytes
+}
+
+// FormatBytes форматирует размер данных в байтах для вывода
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTP"[exp])
+}
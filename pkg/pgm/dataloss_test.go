@@ -0,0 +1,24 @@
+package pgm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", FormatBytes(512))
+	assert.Equal(t, "1.5 KB", FormatBytes(1536))
+	assert.Equal(t, "2.0 MB", FormatBytes(2*1024*1024))
+	assert.Equal(t, "3.0 GB", FormatBytes(3*1024*1024*1024))
+}
+
+func Test_TotalDataLoss(t *testing.T) {
+	rows, bytes := TotalDataLoss([]DataLoss{
+		{Migration: "2_b", Table: "public.orders", Rows: 1200, Bytes: 4096},
+		{Migration: "2_b", Table: "public.users", Column: "note", Rows: 10, Bytes: 80},
+	})
+	assert.Equal(t, int64(1210), rows)
+	assert.Equal(t, int64(4176), bytes)
+	assert.Equal(t, "column public.users.note", DataLoss{Table: "public.users", Column: "note"}.Object())
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"fmt"
 	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
 )
 
-// ApplyMigration применяет миграцию
+// ApplyMigration применяет миграцию.
+// Плейсхолдеры ${name} в up и down sql заменяются значениями из vars до выполнения,
+// down sql сохраняется уже после подстановки, чтобы откат не зависел от окружения.
+// Контрольная сумма checksum считается вызывающим по исходному up sql файла.
+// При выполнении по одному запросу возвращает результаты запросов up sql.
 func ApplyMigration(
 	ctx context.Context,
 	tx pgx.Tx,
@@ -14,23 +19,100 @@ func ApplyMigration(
 	migrationsTableNameWithSchema string,
 	upSql string,
 	downSql string,
-) error {
-	_, err := tx.Exec(ctx, upSql)
+	checksum string,
+	opts ExecOptions,
+) ([]pgm.StatementResult, error) {
+	resolvedUpSql, err := pgm.Substitute(upSql, opts.Vars)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("up sql: %w", err)
+	}
+
+	resolvedDownSql, err := pgm.Substitute(downSql, opts.Vars)
+	if err != nil {
+		return nil, fmt.Errorf("down sql: %w", err)
+	}
+
+	statements, err := ExecSql(ctx, tx, resolvedUpSql, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	if _, err = tx.Exec(
 		ctx,
 		fmt.Sprintf(
-			`INSERT INTO %s (migration_name, created_at, down_sql) VALUES ($1, CURRENT_TIMESTAMP(3), $2);`,
+			`INSERT INTO %s (migration_name, created_at, down_sql, checksum) VALUES ($1, CURRENT_TIMESTAMP(3), $2, $3);`,
 			migrationsTableNameWithSchema,
 		),
 		migrationName,
-		downSql,
+		resolvedDownSql,
+		checksum,
+	); err != nil {
+		return nil, err
+	}
+
+	return statements, nil
+}
+
+// ApplyGoMigration применяет go миграцию
+func ApplyGoMigration(
+	ctx context.Context,
+	tx pgx.Tx,
+	migration pgm.Migration,
+	migrationsTableNameWithSchema string,
+) error {
+	if err := migration.UpFunc(ctx, tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO %s (migration_name, created_at, down_sql, kind) VALUES ($1, CURRENT_TIMESTAMP(3), '', $2);`,
+			migrationsTableNameWithSchema,
+		),
+		migration.Name,
+		string(pgm.GO),
 	); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ApplyRepeatableMigration применяет повторяемую миграцию и сохраняет ее контрольную сумму
+func ApplyRepeatableMigration(
+	ctx context.Context,
+	tx pgx.Tx,
+	migrationName string,
+	migrationsTableNameWithSchema string,
+	upSql string,
+	checksum string,
+	opts ExecOptions,
+) ([]pgm.StatementResult, error) {
+	resolvedUpSql, err := pgm.Substitute(upSql, opts.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := ExecSql(ctx, tx, resolvedUpSql, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO %s (migration_name, created_at, down_sql, kind, checksum)
+			VALUES ($1, CURRENT_TIMESTAMP(3), '', $2, $3)
+			ON CONFLICT (migration_name) DO UPDATE SET created_at = EXCLUDED.created_at, checksum = EXCLUDED.checksum;`,
+			migrationsTableNameWithSchema,
+		),
+		migrationName,
+		string(pgm.REPEATABLE),
+		checksum,
+	); err != nil {
+		return nil, err
+	}
+
+	return statements, nil
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+// BackupSchemaPrefix префикс имени схемы резервных копий таблиц
+const BackupSchemaPrefix = "pgm_backup_"
+
+// BackupsTableSuffix суффикс, добавляемый к имени таблицы миграций для имени таблицы учета резервных копий
+const BackupsTableSuffix = "_backups"
+
+// maxIdentifierLength максимальная длина идентификатора postgres
+const maxIdentifierLength = 63
+
+// EnsureBackupsTable создает таблицу учета резервных копий, если она не существует
+func EnsureBackupsTable(ctx context.Context, tx pgx.Tx, backupsTbl string) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			backup_schema VARCHAR(63) NOT NULL,
+			backup_table VARCHAR(63) NOT NULL,
+			source_table TEXT NOT NULL,
+			migration_name VARCHAR(512) NOT NULL,
+			row_count BIGINT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+			PRIMARY KEY (backup_schema, backup_table)
+		);`,
+		backupsTbl,
+	))
+	return err
+}
+
+// backupSchemaName возвращает имя схемы резервных копий по времени now со случайным суффиксом.
+// Суффикс нужен, потому что параллельные запуски, например тенанты одной базы данных, могут начать откат
+// в одну и ту же секунду, а незафиксированные схемы друг друга они не видят.
+func backupSchemaName(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	return BackupSchemaPrefix + now.UTC().Format("20060102_150405") + "_" + hex.EncodeToString(suffix), nil
+}
+
+// CreateBackupSchema создает схему резервных копий с именем по времени now
+func CreateBackupSchema(ctx context.Context, tx pgx.Tx, now time.Time) (string, error) {
+	schema, err := backupSchemaName(now)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, "CREATE SCHEMA "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		return "", err
+	}
+
+	return schema, nil
+}
+
+// ResolveTable возвращает схему и имя таблицы name с учетом search_path. Если таблица не найдена, found равен false.
+func ResolveTable(ctx context.Context, tx pgx.Tx, name string) (schema string, table string, found bool, err error) {
+	err = tx.QueryRow(ctx, `
+		SELECT n.nspname, c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1) AND c.relkind IN ('r', 'p')`,
+		name,
+	).Scan(&schema, &table)
+	if err == pgx.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+
+	return schema, table, true, nil
+}
+
+// backupTableName возвращает имя копии таблицы schema.table в виде {schema}__{table}.
+// Если по имени нельзя однозначно восстановить схему и таблицу, то есть в них есть "__"
+// или подчеркивание примыкает к разделителю, к имени добавляется хеш пары.
+// Длинные имена сокращаются по границе символа, и к ним тоже добавляется хеш.
+func backupTableName(schema string, table string) string {
+	name := schema + "__" + table
+	ambiguous := strings.Contains(schema, "__") || strings.Contains(table, "__") ||
+		strings.HasSuffix(schema, "_") || strings.HasPrefix(table, "_")
+	if !ambiguous && len(name) <= maxIdentifierLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(schema + "\x00" + table))
+	hash := "_" + hex.EncodeToString(sum[:4])
+
+	n := min(len(name), maxIdentifierLength-len(hash))
+	for n > 0 && n < len(name) && !utf8.RuneStart(name[n]) {
+		n--
+	}
+	return name[:n] + hash
+}
+
+// BackupTable копирует строки таблицы schema.table в схему резервных копий backupSchema
+// и записывает копию в таблицу учета резервных копий
+func BackupTable(
+	ctx context.Context,
+	tx pgx.Tx,
+	backupsTbl string,
+	backupSchema string,
+	schema string,
+	table string,
+	migName string,
+) (*pgm.Backup, error) {
+	backup := &pgm.Backup{
+		Schema:    backupSchema,
+		Table:     backupTableName(schema, table),
+		Source:    schema + "." + table,
+		Migration: migName,
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE %s AS TABLE %s",
+		pgx.Identifier{backup.Schema, backup.Table}.Sanitize(),
+		pgx.Identifier{schema, table}.Sanitize(),
+	))
+	if err != nil {
+		return nil, err
+	}
+	backup.Rows = tag.RowsAffected()
+
+	err = tx.QueryRow(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO %s (backup_schema, backup_table, source_table, migration_name, row_count)
+			VALUES ($1, $2, $3, $4, $5) RETURNING created_at`,
+			backupsTbl,
+		),
+		backup.Schema,
+		backup.Table,
+		backup.Source,
+		backup.Migration,
+		backup.Rows,
+	).Scan(&backup.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// GetBackups считывает резервные копии таблиц из таблицы учета. Если таблицы учета нет, копий нет.
+func GetBackups(ctx context.Context, tx pgx.Tx, backupsTbl string) ([]pgm.Backup, error) {
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", backupsTbl).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	backups := make([]pgm.Backup, 0)
+	if !exists {
+		return backups, nil
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		`SELECT backup_schema, backup_table, source_table, migration_name, row_count, created_at
+		FROM %s ORDER BY created_at, backup_schema, backup_table`,
+		backupsTbl,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b pgm.Backup
+		if err = rows.Scan(&b.Schema, &b.Table, &b.Source, &b.Migration, &b.Rows, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+
+	return backups, rows.Err()
+}
+
+// DropBackupSchema удаляет схему резервных копий вместе с копиями и ее записи из таблицы учета
+func DropBackupSchema(ctx context.Context, tx pgx.Tx, backupsTbl string, schema string) error {
+	if _, err := tx.Exec(ctx, "DROP SCHEMA IF EXISTS "+pgx.Identifier{schema}.Sanitize()+" CASCADE"); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE backup_schema = $1", backupsTbl), schema)
+	return err
+}
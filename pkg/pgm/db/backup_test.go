@@ -0,0 +1,39 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupTableName(t *testing.T) {
+	require.Equal(t, "public__orders", backupTableName("public", "orders"))
+
+	long := backupTableName("tenant_with_a_rather_long_schema_name", "table_with_an_even_longer_name")
+	require.Len(t, long, maxIdentifierLength)
+	require.True(t, strings.HasPrefix(long, "tenant_with_a_rather_long_schema_name__table_with_an_"))
+	require.NotEqual(t, long, backupTableName("tenant_with_a_rather_long_schema_name", "table_with_an_even_longer_name2"))
+
+	require.NotEqual(t, backupTableName("a__b", "c"), backupTableName("a", "b__c"))
+	require.NotEqual(t, backupTableName("a_", "b"), backupTableName("a", "_b"))
+	require.NotEqual(t, "a__b__c", backupTableName("a__b", "c"))
+
+	multibyte := backupTableName("схема_с_длинным_именем", "таблица_с_длинным_именем")
+	require.LessOrEqual(t, len(multibyte), maxIdentifierLength)
+	require.True(t, utf8.ValidString(multibyte))
+}
+
+func TestBackupSchemaName(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	first, err := backupSchemaName(now)
+	require.NoError(t, err)
+	require.Regexp(t, `^pgm_backup_20261019_120000_[0-9a-f]{8}$`, first)
+
+	second, err := backupSchemaName(now)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+)
+
+// notExtensionMember условие, исключающее объекты, созданные расширениями
+const notExtensionMember = `NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = '%s'::regclass AND d.objid = %s AND d.deptype = 'e')`
+
+var (
+	// схемы резервных копий таблиц не относятся к схеме базы данных
+	userSchemasQuery = `
+		SELECT n.nspname FROM pg_namespace n
+		WHERE n.nspname <> 'information_schema' AND n.nspname NOT LIKE 'pg\_%'
+			AND n.nspname NOT LIKE '` + strings.ReplaceAll(BackupSchemaPrefix, "_", `\_`) + `%'
+		ORDER BY n.nspname`
+
+	schemasQuery = `
+		SELECT quote_ident(n.nspname), format('CREATE SCHEMA IF NOT EXISTS %I', n.nspname)
+		FROM pg_namespace n
+		WHERE n.nspname = ANY($1) AND n.nspname <> 'public'`
+
+	extensionsQuery = `
+		SELECT quote_ident(e.extname), format('CREATE EXTENSION IF NOT EXISTS %I WITH SCHEMA %I', e.extname, n.nspname)
+		FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE n.nspname = ANY($1)`
+
+	typesQuery = `
+		SELECT format('%I.%I', n.nspname, t.typname),
+			CASE t.typtype
+			WHEN 'e' THEN format('CREATE TYPE %I.%I AS ENUM (%s)', n.nspname, t.typname,
+				(SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder) FROM pg_enum e WHERE e.enumtypid = t.oid))
+			WHEN 'd' THEN format('CREATE DOMAIN %I.%I AS %s', n.nspname, t.typname, format_type(t.typbasetype, t.typtypmod))
+				|| CASE WHEN t.typnotnull THEN ' NOT NULL' ELSE '' END
+				|| COALESCE(' DEFAULT ' || t.typdefault, '')
+				|| COALESCE((SELECT string_agg(format(' CONSTRAINT %I %s', c.conname, pg_get_constraintdef(c.oid, true)), '' ORDER BY c.conname)
+					FROM pg_constraint c WHERE c.contypid = t.oid AND c.contype = 'c'), '')
+			ELSE format('CREATE TYPE %I.%I AS (%s)', n.nspname, t.typname,
+				(SELECT string_agg(format('%I %s', a.attname, format_type(a.atttypid, a.atttypmod)), ', ' ORDER BY a.attnum)
+				FROM pg_attribute a WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped))
+			END
+		FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = ANY($1)
+			AND (t.typtype IN ('e', 'd') OR (t.typtype = 'c' AND EXISTS (SELECT 1 FROM pg_class c WHERE c.oid = t.typrelid AND c.relkind = 'c')))
+			AND ` + fmt.Sprintf(notExtensionMember, "pg_type", "t.oid")
+
+	sequencesQuery = `
+		SELECT format('%I.%I', n.nspname, c.relname),
+			format('CREATE SEQUENCE %I.%I AS %s START WITH %s INCREMENT BY %s MINVALUE %s MAXVALUE %s CACHE %s%s',
+				n.nspname, c.relname, format_type(s.seqtypid, NULL), s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache,
+				CASE WHEN s.seqcycle THEN ' CYCLE' ELSE '' END)
+		FROM pg_sequence s JOIN pg_class c ON c.oid = s.seqrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype IN ('i', 'e'))`
+
+	tablesQuery = `
+		SELECT format('%I.%I', n.nspname, c.relname),
+			CASE WHEN c.relispartition THEN format('PARTITION OF %s %s',
+				(SELECT format('%I.%I', pn.nspname, pc.relname) FROM pg_inherits i
+					JOIN pg_class pc ON pc.oid = i.inhparent JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+					WHERE i.inhrelid = c.oid),
+				pg_get_expr(c.relpartbound, c.oid)) ELSE '' END
+			|| CASE WHEN c.relispartition AND c.relkind = 'p' THEN ' ' ELSE '' END
+			|| CASE WHEN c.relkind = 'p' THEN 'PARTITION BY ' || pg_get_partkeydef(c.oid) ELSE '' END
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND n.nspname = ANY($1) AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid")
+
+	columnsQuery = `
+		SELECT format('%I.%I', n.nspname, c.relname), quote_ident(a.attname), format_type(a.atttypid, a.atttypmod), a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), ''), a.attidentity::text, a.attgenerated::text
+		FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attnum > 0 AND NOT a.attisdropped AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+			AND n.nspname = ANY($1) AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid") + `
+		ORDER BY n.nspname, c.relname, a.attnum`
+
+	constraintsQuery = `
+		SELECT format('%I.%I', n.nspname, c.relname), quote_ident(con.conname), pg_get_constraintdef(con.oid, true), con.contype::text
+		FROM pg_constraint con JOIN pg_class c ON c.oid = con.conrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND con.contype IN ('p', 'u', 'c', 'f', 'x') AND con.conislocal AND con.conparentid = 0
+			AND n.nspname = ANY($1) AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid")
+
+	indexesQuery = `
+		SELECT format('%I.%I', n.nspname, t.relname), quote_ident(i.relname), pg_get_indexdef(i.oid)
+		FROM pg_index x
+			JOIN pg_class i ON i.oid = x.indexrelid
+			JOIN pg_class t ON t.oid = x.indrelid
+			JOIN pg_namespace n ON n.oid = i.relnamespace
+		WHERE n.nspname = ANY($1) AND t.relkind IN ('r', 'p', 'm')
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint con
+				WHERE con.conindid = x.indexrelid AND con.conrelid = x.indrelid AND con.contype IN ('p', 'u', 'x'))
+			AND NOT EXISTS (SELECT 1 FROM pg_inherits inh WHERE inh.inhrelid = i.oid)
+			AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "t.oid")
+
+	functionsQuery = `
+		SELECT format('%I.%I(%s)', n.nspname, p.proname, pg_get_function_identity_arguments(p.oid)), pg_get_functiondef(p.oid)
+		FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = ANY($1) AND p.prokind IN ('f', 'p') AND ` + fmt.Sprintf(notExtensionMember, "pg_proc", "p.oid")
+
+	viewsQuery = `
+		SELECT format('%I.%I', n.nspname, c.relname),
+			format(E'CREATE %sVIEW %I.%I AS\n%s',
+				CASE WHEN c.relkind = 'm' THEN 'MATERIALIZED ' ELSE '' END, n.nspname, c.relname, rtrim(pg_get_viewdef(c.oid, true), ';'))
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND n.nspname = ANY($1) AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid")
+
+	triggersQuery = `
+		SELECT format('%I.%I', n.nspname, c.relname), quote_ident(t.tgname), pg_get_triggerdef(t.oid, true)
+		FROM pg_trigger t JOIN pg_class c ON c.oid = t.tgrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT t.tgisinternal AND t.tgparentid = 0 AND n.nspname = ANY($1)`
+
+	grantsQuery = `
+		WITH acl AS (
+			SELECT 'SCHEMA ' || quote_ident(n.nspname) AS object, n.nspowner AS owner, n.nspacl AS acl
+			FROM pg_namespace n WHERE n.nspname = ANY($1)
+			UNION ALL
+			SELECT CASE c.relkind WHEN 'S' THEN 'SEQUENCE ' ELSE 'TABLE ' END || format('%I.%I', n.nspname, c.relname), c.relowner, c.relacl
+			FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p', 'v', 'm', 'S') AND n.nspname = ANY($1)
+			UNION ALL
+			SELECT CASE p.prokind WHEN 'p' THEN 'PROCEDURE ' ELSE 'FUNCTION ' END
+				|| format('%I.%I(%s)', n.nspname, p.proname, pg_get_function_identity_arguments(p.oid)), p.proowner, p.proacl
+			FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE p.prokind IN ('f', 'p') AND n.nspname = ANY($1)
+		)
+		SELECT acl.object,
+			CASE WHEN e.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(r.rolname) END,
+			string_agg(e.privilege_type, ', ' ORDER BY e.privilege_type),
+			e.is_grantable
+		FROM acl CROSS JOIN LATERAL aclexplode(acl.acl) e LEFT JOIN pg_roles r ON r.oid = e.grantee
+		WHERE acl.acl IS NOT NULL AND e.grantee <> acl.owner
+		GROUP BY acl.object, e.grantee, r.rolname, e.is_grantable`
+)
+
+// scanNamed читает объект верхнего уровня: имя и определение
+func scanNamed(kind catalog.Kind) pgx.RowToFunc[catalog.Object] {
+	return func(row pgx.CollectableRow) (catalog.Object, error) {
+		o := catalog.Object{Kind: kind}
+		err := row.Scan(&o.Name, &o.Definition)
+		return o, err
+	}
+}
+
+// scanChild читает объект таблицы: таблицу, имя и определение
+func scanChild(kind catalog.Kind) pgx.RowToFunc[catalog.Object] {
+	return func(row pgx.CollectableRow) (catalog.Object, error) {
+		o := catalog.Object{Kind: kind}
+		err := row.Scan(&o.Parent, &o.Name, &o.Definition)
+		return o, err
+	}
+}
+
+func scanColumn(row pgx.CollectableRow) (catalog.Object, error) {
+	var table, name, dataType, defaultExpr, identity, generated string
+	var notNull bool
+	if err := row.Scan(&table, &name, &dataType, &notNull, &defaultExpr, &identity, &generated); err != nil {
+		return catalog.Object{}, err
+	}
+
+	return catalog.Column(table, name, dataType, notNull, defaultExpr, identity, generated), nil
+}
+
+func scanConstraint(row pgx.CollectableRow) (catalog.Object, error) {
+	o := catalog.Object{Kind: catalog.CONSTRAINT}
+	var contype string
+	if err := row.Scan(&o.Parent, &o.Name, &o.Definition, &contype); err != nil {
+		return o, err
+	}
+
+	o.Attributes = map[string]string{"type": contype}
+	return o, nil
+}
+
+func scanGrant(row pgx.CollectableRow) (catalog.Object, error) {
+	o := catalog.Object{Kind: catalog.GRANT}
+	var grantee, privileges string
+	var grantable bool
+	if err := row.Scan(&o.Parent, &grantee, &privileges, &grantable); err != nil {
+		return o, err
+	}
+
+	o.Name = grantee
+	if grantable {
+		o.Name += " WITH GRANT OPTION"
+	}
+	o.Definition = fmt.Sprintf("GRANT %s ON %s TO %s", privileges, o.Parent, o.Name)
+
+	return o, nil
+}
+
+// ReadCatalog читает объекты схем schemas из системного каталога. Если schemas пуст, читаются все
+// пользовательские схемы. Объекты расширений не включаются, имена в определениях квалифицируются схемой.
+func ReadCatalog(ctx context.Context, conn Conn, schemas []string) (result *catalog.Catalog, err error) {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		rollbackErr := tx.Rollback(ctx)
+		if !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, rollbackErr)
+		}
+	}()
+
+	return ReadCatalogTx(ctx, tx, schemas)
+}
+
+// ReadCatalogTx читает объекты схем schemas в транзакции tx, например в транзакции миграций,
+// чтобы увидеть еще не зафиксированные изменения. Настройки, измененные при чтении, откатываются.
+func ReadCatalogTx(ctx context.Context, parent pgx.Tx, schemas []string) (result *catalog.Catalog, err error) {
+	tx, err := parent.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		rollbackErr := tx.Rollback(ctx)
+		if !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, rollbackErr)
+		}
+	}()
+
+	// с пустым search_path функции pg_get_* квалифицируют схемой все имена, и снимок не зависит от настроек роли
+	if _, err = tx.Exec(ctx, `SELECT set_config('search_path', '', true);`); err != nil {
+		return nil, err
+	}
+
+	if len(schemas) == 0 {
+		rows, err := tx.Query(ctx, userSchemasQuery)
+		if err != nil {
+			return nil, err
+		}
+		if schemas, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+			return nil, err
+		}
+	}
+
+	queries := []struct {
+		kind  catalog.Kind
+		query string
+		scan  pgx.RowToFunc[catalog.Object]
+	}{
+		{catalog.SCHEMA, schemasQuery, scanNamed(catalog.SCHEMA)},
+		{catalog.EXTENSION, extensionsQuery, scanNamed(catalog.EXTENSION)},
+		{catalog.TYPE, typesQuery, scanNamed(catalog.TYPE)},
+		{catalog.SEQUENCE, sequencesQuery, scanNamed(catalog.SEQUENCE)},
+		{catalog.TABLE, tablesQuery, scanNamed(catalog.TABLE)},
+		{catalog.COLUMN, columnsQuery, scanColumn},
+		{catalog.CONSTRAINT, constraintsQuery, scanConstraint},
+		{catalog.INDEX, indexesQuery, scanChild(catalog.INDEX)},
+		{catalog.FUNCTION, functionsQuery, scanNamed(catalog.FUNCTION)},
+		{catalog.VIEW, viewsQuery, scanNamed(catalog.VIEW)},
+		{catalog.TRIGGER, triggersQuery, scanChild(catalog.TRIGGER)},
+		{catalog.GRANT, grantsQuery, scanGrant},
+	}
+
+	objects := make([]catalog.Object, 0)
+	for _, q := range queries {
+		rows, err := tx.Query(ctx, q.query, schemas)
+		if err != nil {
+			return nil, fmt.Errorf("read %s catalog error: %w", q.kind, err)
+		}
+
+		found, err := pgx.CollectRows(rows, q.scan)
+		if err != nil {
+			return nil, fmt.Errorf("read %s catalog error: %w", q.kind, err)
+		}
+		objects = append(objects, found...)
+	}
+
+	return catalog.New(objects), nil
+}
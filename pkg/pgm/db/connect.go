@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"golang.org/x/exp/slog"
 	"regexp"
 )
 
@@ -85,20 +87,47 @@ func removeDatabaseFromConnectionString(connectionString, database string) strin
 	return re.ReplaceAllString(connectionString, "$1$3")
 }
 
-func Connect(ctx context.Context, connectionString string) (*pgxpool.Pool, error) {
+// ConnectOption настройка пула соединений
+type ConnectOption func(cfg *pgxpool.Config)
+
+// WithQueryTracer задает трассировщик запросов соединений пула
+func WithQueryTracer(tracer pgx.QueryTracer) ConnectOption {
+	return func(cfg *pgxpool.Config) {
+		cfg.ConnConfig.Tracer = tracer
+	}
+}
+
+// Connect подключается к базе данных, при необходимости создавая ее.
+// Ошибки подключения возвращаются как *pgm.ConnectionError.
+func Connect(ctx context.Context, connectionString string, opts ...ConnectOption) (*pgxpool.Pool, error) {
 	err := preconnect(ctx, connectionString)
 	if err != nil {
-		fmt.Printf("Preconnect warning: %v (attempting to connect anyway)\n", err)
+		slog.Warn("preconnect failed, attempting to connect anyway", "error", err)
+	}
+
+	return ConnectExisting(ctx, connectionString, opts...)
+}
+
+// ConnectExisting подключается к базе данных, не создавая ее.
+// Ошибки подключения возвращаются как *pgm.ConnectionError.
+func ConnectExisting(ctx context.Context, connectionString string, opts ...ConnectOption) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(connectionString)
+	if err != nil {
+		return nil, &pgm.ConnectionError{Err: fmt.Errorf("failed to connect to database: %w", err)}
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
 	}
 
-	conn, err := pgxpool.New(ctx, connectionString)
+	conn, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, &pgm.ConnectionError{Err: fmt.Errorf("failed to connect to database: %w", err)}
 	}
 
 	if err := conn.Ping(ctx); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("database ping failed: %w", err)
+		return nil, &pgm.ConnectionError{Err: fmt.Errorf("database ping failed: %w", err)}
 	}
 
 	return conn, nil
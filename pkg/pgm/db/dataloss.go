@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// tableSizeQuery оценивает количество строк и размер таблицы вместе с ее секциями по статистике postgres
+const tableSizeQuery = `
+SELECT count(*),
+	COALESCE(sum(GREATEST(c.reltuples, s.n_live_tup, 0)), 0)::bigint,
+	COALESCE(sum(pg_total_relation_size(c.oid)), 0)::bigint
+FROM pg_partition_tree(to_regclass($1)) p
+JOIN pg_class c ON c.oid = p.relid
+LEFT JOIN pg_stat_user_tables s ON s.relid = p.relid
+WHERE p.isleaf`
+
+// columnWidthQuery возвращает средний размер значения столбца по статистике postgres
+const columnWidthQuery = `
+SELECT COALESCE((
+	SELECT st.avg_width
+	FROM pg_stats st
+	WHERE st.schemaname = n.nspname AND st.tablename = c.relname AND st.attname = $2
+	ORDER BY st.inherited DESC
+	LIMIT 1
+), 0)
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.oid = to_regclass($1)`
+
+// columnName приводит имя столбца из sql к имени в каталоге postgres
+func columnName(name string) string {
+	if len(name) > 1 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return strings.ToLower(name)
+}
+
+// EstimateDataLoss оценивает количество строк и размер данных таблицы table или ее столбца column по статистике postgres.
+// Если таблица не найдена, found равен false. Без статистики столбца размер его данных равен 0.
+func EstimateDataLoss(ctx context.Context, tx pgx.Tx, table string, column string) (found bool, rows int64, bytes int64, err error) {
+	var relations int
+	if err = tx.QueryRow(ctx, tableSizeQuery, table).Scan(&relations, &rows, &bytes); err != nil {
+		return false, 0, 0, err
+	}
+
+	if relations == 0 {
+		return false, 0, 0, nil
+	}
+
+	if column == "" {
+		return true, rows, bytes, nil
+	}
+
+	var width int64
+	if err = tx.QueryRow(ctx, columnWidthQuery, table, columnName(column)).Scan(&width); err != nil {
+		return false, 0, 0, err
+	}
+
+	return true, rows, rows * width, nil
+}
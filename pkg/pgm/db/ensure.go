@@ -4,13 +4,12 @@ import (
 	"context"
 	"fmt"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // EnsureMigrationsTable создает схему и таблицу миграций если они не существуют
 func EnsureMigrationsTable(
 	ctx context.Context,
-	conn *pgxpool.Pool,
+	conn Conn,
 	migrationsTableSchemaName string,
 	migrationsTableName string,
 ) error {
@@ -23,11 +22,17 @@ func EnsureMigrationsTable(
 				down_sql TEXT NOT NULL,
 				CONSTRAINT "%s_pk" PRIMARY KEY (migration_name)
 			);
+			ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS kind VARCHAR(16) NOT NULL DEFAULT 'sql';
+			ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS checksum VARCHAR(64) NOT NULL DEFAULT '';
 		`,
 			migrationsTableSchemaName,
 			migrationsTableSchemaName,
 			migrationsTableName,
 			migrationsTableName,
+			migrationsTableSchemaName,
+			migrationsTableName,
+			migrationsTableSchemaName,
+			migrationsTableName,
 		),
 	)
 
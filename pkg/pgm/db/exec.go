@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+	"golang.org/x/exp/slog"
+)
+
+// copyFromStdinRegexp признак COPY ... FROM STDIN, данные которого передаются только через протокол копирования
+var copyFromStdinRegexp = regexp.MustCompile(`(?i)\bFROM\s+STDIN\b`)
+
+// ExecOptions параметры выполнения sql миграции
+type ExecOptions struct {
+	// Vars значения плейсхолдеров ${name}
+	Vars map[string]string
+	// SplitStatements включает выполнение sql по одному запросу
+	SplitStatements bool
+	// StatementTimeout ограничение времени выполнения запроса, 0 - без ограничения
+	StatementTimeout time.Duration
+}
+
+// setStatementTimeout устанавливает statement_timeout в пределах транзакции и возвращает прежнее значение
+func setStatementTimeout(ctx context.Context, tx pgx.Tx, timeout time.Duration) (string, error) {
+	var prev string
+	if err := tx.QueryRow(ctx, `SELECT current_setting('statement_timeout');`).Scan(&prev); err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true);`, fmt.Sprintf("%dms", timeout.Milliseconds())); err != nil {
+		return "", err
+	}
+
+	return prev, nil
+}
+
+// execStatement выполняет отдельный запрос. Данные COPY ... FROM STDIN передаются через протокол копирования.
+func execStatement(ctx context.Context, tx pgx.Tx, statement sqlsplit.Statement) error {
+	if statement.Meta {
+		return errors.New("psql meta-commands must be expanded before execution")
+	}
+
+	if statement.IsCopyFromStdin() {
+		_, err := tx.Conn().PgConn().CopyFrom(ctx, strings.NewReader(statement.CopyData), statement.SQL)
+		return err
+	}
+
+	_, err := tx.Exec(ctx, statement.SQL)
+	return err
+}
+
+// execStatements разбивает sql на запросы и выполняет их по одному
+func execStatements(ctx context.Context, tx pgx.Tx, sql string) ([]pgm.StatementResult, error) {
+	statements, err := sqlsplit.Split(sql)
+	if err != nil {
+		return nil, fmt.Errorf("split sql: %w", err)
+	}
+
+	results := make([]pgm.StatementResult, 0, len(statements))
+	for i, statement := range statements {
+		started := time.Now()
+
+		if err = execStatement(ctx, tx, statement); err != nil {
+			return nil, &ExecError{Sql: statement.SQL, LineOffset: statement.Line - 1, Statement: true, Err: err}
+		}
+
+		result := pgm.StatementResult{Line: statement.Line, SQL: statement.SQL, Duration: time.Since(started)}
+		results = append(results, result)
+
+		slog.Debug(
+			"statement executed",
+			"migration", MigrationName(ctx),
+			"statement", fmt.Sprintf("%d/%d", i+1, len(statements)),
+			"line", result.Line,
+			"duration", result.Duration,
+		)
+	}
+
+	return results, nil
+}
+
+// ExecSql выполняет sql миграции целиком или по одному запросу, если включен SplitStatements.
+// Sql с COPY ... FROM STDIN всегда выполняется по одному запросу.
+// Плейсхолдеры не подставляются, sql должен быть уже разрешен.
+// Все запросы выполняются в транзакции tx, statement_timeout действует только на время выполнения sql.
+func ExecSql(ctx context.Context, tx pgx.Tx, sql string, opts ExecOptions) ([]pgm.StatementResult, error) {
+	var prevTimeout string
+	if opts.StatementTimeout > 0 {
+		var err error
+		if prevTimeout, err = setStatementTimeout(ctx, tx, opts.StatementTimeout); err != nil {
+			return nil, fmt.Errorf("set statement timeout: %w", err)
+		}
+	}
+
+	var results []pgm.StatementResult
+	if opts.SplitStatements || copyFromStdinRegexp.MatchString(sql) {
+		var err error
+		if results, err = execStatements(ctx, tx, sql); err != nil {
+			return nil, err
+		}
+	} else if _, err := tx.Exec(ctx, sql); err != nil {
+		return nil, &ExecError{Sql: sql, Err: err}
+	}
+
+	if opts.StatementTimeout > 0 {
+		if _, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true);`, prevTimeout); err != nil {
+			return nil, fmt.Errorf("restore statement timeout: %w", err)
+		}
+	}
+
+	return results, nil
+}
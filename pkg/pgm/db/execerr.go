@@ -0,0 +1,35 @@
+package db
+
+// ExecError ошибка выполнения sql миграции. Хранит выполненный sql,
+// чтобы по позиции ошибки postgres можно было найти место ошибки.
+type ExecError struct {
+	Sql string
+	// LineOffset количество строк миграции, предшествующих Sql, при выполнении по одному запросу
+	LineOffset int
+	// Statement ошибка отдельного запроса при выполнении по одному запросу
+	Statement bool
+	Err       error
+}
+
+func (e *ExecError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// RevertError ошибка выполнения сохраненного down sql при откате.
+// Хранит сохраненный down sql, чтобы его можно было сравнить с файлом миграции.
+type RevertError struct {
+	DownSql string
+	Err     error
+}
+
+func (e *RevertError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RevertError) Unwrap() error {
+	return e.Err
+}
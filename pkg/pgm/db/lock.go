@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"fmt"
+	"time"
+
 	"github.com/jackc/pgx/v5"
 )
 
@@ -15,3 +17,33 @@ func LockMigrationsTable(ctx context.Context, tx pgx.Tx, migrationsTableNameWith
 
 	return err
 }
+
+// LockMigrationsTableWithTimeout блокирует таблицу миграций, ожидая блокировку не дольше timeout.
+// После получения блокировки lock_timeout транзакции возвращается к прежнему значению.
+func LockMigrationsTableWithTimeout(
+	ctx context.Context,
+	tx pgx.Tx,
+	migrationsTableNameWithSchema string,
+	timeout time.Duration,
+) error {
+	if timeout <= 0 {
+		return LockMigrationsTable(ctx, tx, migrationsTableNameWithSchema)
+	}
+
+	var prevTimeout string
+	if err := tx.QueryRow(ctx, "SELECT current_setting('lock_timeout')").Scan(&prevTimeout); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('lock_timeout', $1, true)", fmt.Sprintf("%dms", timeout.Milliseconds())); err != nil {
+		return err
+	}
+
+	if err := LockMigrationsTable(ctx, tx, migrationsTableNameWithSchema); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, "SELECT set_config('lock_timeout', $1, true)", prevTimeout)
+
+	return err
+}
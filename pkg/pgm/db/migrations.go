@@ -3,17 +3,81 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/quadgod/pgm/pkg/pgm"
 )
 
-// GetMigrations считывает список миграций из базы данных
-func GetMigrations(ctx context.Context, tx pgx.Tx, migTbl string) ([]pgm.Migration, error) {
-	rows, err := tx.Query(ctx, fmt.Sprintf(
-		`SELECT migration_name, down_sql FROM %s ORDER BY migration_name ASC;`,
+// migrationsColumns возвращает выражения для столбцов kind и checksum таблицы миграций migTbl.
+// Таблица, созданная до их появления, получает их только при записи в EnsureMigrationsTable,
+// поэтому при чтении отсутствующие столбцы заменяются значениями по умолчанию.
+func migrationsColumns(ctx context.Context, tx pgx.Tx, migTbl string) (kind string, checksum string, err error) {
+	schema, table, _ := strings.Cut(migTbl, ".")
+
+	rows, err := tx.Query(
+		ctx,
+		`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2 AND column_name IN ('kind', 'checksum');`,
+		schema,
+		table,
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", "", err
+	}
+
+	kind, checksum = "'sql'", "''"
+	for _, column := range columns {
+		switch column {
+		case "kind":
+			kind = "kind"
+		case "checksum":
+			checksum = "checksum"
+		}
+	}
+
+	return kind, checksum, nil
+}
+
+// GetMigrations считывает список миграций из базы данных без повторяемых миграций
+func GetMigrations(ctx context.Context, tx pgx.Tx, migTbl string) ([]pgm.AppliedMigration, error) {
+	kind, checksum, err := migrationsColumns(ctx, tx, migTbl)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryMigrations(ctx, tx, fmt.Sprintf(
+		`SELECT migration_name, down_sql, %s, %s, created_at FROM %s WHERE %s <> $1 ORDER BY migration_name ASC;`,
+		kind,
+		checksum,
 		migTbl,
+		kind,
 	))
+}
+
+// GetRepeatableMigrations считывает список примененных повторяемых миграций
+func GetRepeatableMigrations(ctx context.Context, tx pgx.Tx, migTbl string) ([]pgm.AppliedMigration, error) {
+	kind, checksum, err := migrationsColumns(ctx, tx, migTbl)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryMigrations(ctx, tx, fmt.Sprintf(
+		`SELECT migration_name, down_sql, %s, %s, created_at FROM %s WHERE %s = $1 ORDER BY migration_name ASC;`,
+		kind,
+		checksum,
+		migTbl,
+		kind,
+	))
+}
+
+func queryMigrations(ctx context.Context, tx pgx.Tx, query string) ([]pgm.AppliedMigration, error) {
+	rows, err := tx.Query(ctx, query, string(pgm.REPEATABLE))
 
 	if err != nil {
 		return nil, err
@@ -21,11 +85,17 @@ func GetMigrations(ctx context.Context, tx pgx.Tx, migTbl string) ([]pgm.Migrati
 
 	defer rows.Close()
 
-	dbMigrations := make([]pgm.Migration, 0)
+	dbMigrations := make([]pgm.AppliedMigration, 0)
 
 	for rows.Next() {
-		dbMigration := pgm.Migration{Up: ""}
-		err = rows.Scan(&dbMigration.Name, &dbMigration.Down)
+		dbMigration := pgm.AppliedMigration{}
+		err = rows.Scan(
+			&dbMigration.Name,
+			&dbMigration.DownSql,
+			&dbMigration.Kind,
+			&dbMigration.Checksum,
+			&dbMigration.AppliedAt,
+		)
 		if err != nil {
 			return nil, err
 		}
@@ -38,3 +108,13 @@ func GetMigrations(ctx context.Context, tx pgx.Tx, migTbl string) ([]pgm.Migrati
 
 	return dbMigrations, nil
 }
+
+// MigrationsTableExists проверяет существование таблицы миграций
+func MigrationsTableExists(ctx context.Context, tx pgx.Tx, migTbl string) (bool, error) {
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", migTbl).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// protectedComment комментарий таблицы миграций, которым база данных отмечается как защищенная от отката миграций
+const protectedComment = "pgm:protected"
+
+// IsProtected проверяет, отмечена ли база данных как защищенная от отката миграций
+func IsProtected(ctx context.Context, tx pgx.Tx, migTbl string) (bool, error) {
+	var protected bool
+	err := tx.QueryRow(
+		ctx,
+		"SELECT COALESCE(obj_description(to_regclass($1), 'pg_class') = $2, false)",
+		migTbl,
+		protectedComment,
+	).Scan(&protected)
+	if err != nil {
+		return false, err
+	}
+
+	return protected, nil
+}
+
+// SetProtected отмечает базу данных как защищенную от отката миграций или снимает отметку
+func SetProtected(ctx context.Context, tx pgx.Tx, migTbl string, protected bool) error {
+	comment := "NULL"
+	if protected {
+		comment = "'" + protectedComment + "'"
+	}
+
+	_, err := tx.Exec(ctx, fmt.Sprintf("COMMENT ON TABLE %s IS %s", migTbl, comment))
+	return err
+}
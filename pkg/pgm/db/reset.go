@@ -7,6 +7,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/quadgod/pgm/pkg/pgm"
 )
 
 // Reset удаляет все миграции из базы данных
@@ -14,6 +15,7 @@ func Reset(
 	ctx context.Context,
 	tx pgx.Tx,
 	migrationsTableNameWithSchema string,
+	goMigrations map[string]pgm.Migration,
 ) ([]string, error) {
 	revertedNames := make([]string, 0)
 
@@ -27,9 +29,9 @@ func Reset(
 	}
 
 	for i := len(dbMigrations) - 1; i >= 0; i-- {
-		_, err = tx.Exec(ctx, dbMigrations[i].Down)
+		_, err = ExecDown(ctx, tx, dbMigrations[i], goMigrations, ExecOptions{})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("revert %s migration error - can't execute down. %w", dbMigrations[i].Name, err)
 		}
 		revertedNames = append(revertedNames, dbMigrations[i].Name)
 	}
@@ -68,8 +70,13 @@ func ResetForTests(
 		return nil
 	}
 
+	goMigrations := make(map[string]pgm.Migration)
+	for _, m := range pgm.GoMigrations() {
+		goMigrations[m.Name] = m
+	}
+
 	for i := len(dbMigrations) - 1; i >= 0; i-- {
-		_, err = tx.Exec(ctx, dbMigrations[i].Down)
+		_, err = ExecDown(ctx, tx, dbMigrations[i], goMigrations, ExecOptions{})
 		if err != nil {
 			return err
 		}
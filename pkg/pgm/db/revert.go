@@ -8,33 +8,66 @@ import (
 	"github.com/quadgod/pgm/pkg/pgm"
 )
 
+// ExecDown выполняет откат примененной миграции: down sql для sql миграций
+// или зарегистрированную down функцию для go миграций.
+// Сохраненный down sql уже содержит подставленные плейсхолдеры, поэтому opts.Vars не используется.
+func ExecDown(
+	ctx context.Context,
+	tx pgx.Tx,
+	applied pgm.AppliedMigration,
+	goMigrations map[string]pgm.Migration,
+	opts ExecOptions,
+) ([]pgm.StatementResult, error) {
+	if !applied.IsGo() {
+		return ExecSql(ctx, tx, applied.DownSql, opts)
+	}
+
+	goMigration, found := goMigrations[applied.Name]
+	if !found {
+		return nil, fmt.Errorf("go migration %s is not registered", applied.Name)
+	}
+
+	if goMigration.DownFunc == nil {
+		return nil, fmt.Errorf("go migration %s has no registered down function", applied.Name)
+	}
+
+	return nil, goMigration.DownFunc(ctx, tx)
+}
+
 // RevertMigration откатывает миграцию
 func RevertMigration(
 	ctx context.Context,
 	tx pgx.Tx,
 	migTbl string,
 	migName string,
+	goMigrations map[string]pgm.Migration,
+	opts ExecOptions,
 ) (*pgm.MigrationResult, error) {
-	downSqlQuery := fmt.Sprintf(`SELECT down_sql FROM %s WHERE migration_name = $1 LIMIT 1;`, migTbl)
+	downSqlQuery := fmt.Sprintf(`SELECT down_sql, kind FROM %s WHERE migration_name = $1 LIMIT 1;`, migTbl)
 	row := tx.QueryRow(ctx, downSqlQuery, migName)
 
-	var downSql string
-	if err := row.Scan(&downSql); err != nil {
-		return nil, fmt.Errorf("revert %s migration error - down sql was not found. %v", migName, err)
+	applied := pgm.AppliedMigration{Name: migName}
+	if err := row.Scan(&applied.DownSql, &applied.Kind); err != nil {
+		return nil, fmt.Errorf("revert %s migration error - down sql was not found. %w", migName, err)
 	}
 
-	if _, err := tx.Exec(ctx, downSql); err != nil {
-		return nil, fmt.Errorf("revert %s migration error - can't execute down sql. %v", migName, err)
+	statements, err := ExecDown(ctx, tx, applied, goMigrations, opts)
+	if err != nil {
+		return nil, &RevertError{
+			DownSql: applied.DownSql,
+			Err:     fmt.Errorf("revert %s migration error - can't execute down. %w", migName, err),
+		}
 	}
 
 	delMigSql := fmt.Sprintf(`DELETE FROM %s WHERE migration_name = $1;`, migTbl)
 	if _, err := tx.Exec(ctx, delMigSql, migName); err != nil {
-		return nil, fmt.Errorf("revert %s migration error - can't delete record from migrations table. %v", migName, err)
+		return nil, fmt.Errorf("revert %s migration error - can't delete record from migrations table. %w", migName, err)
 	}
 
 	result := new(pgm.MigrationResult)
 	result.MigrationName = migName
 	result.Status = pgm.REVERTED
+	result.Statements = statements
 
 	return result, nil
 }
@@ -45,11 +78,13 @@ func RevertMigrations(
 	tx pgx.Tx,
 	migTbl string,
 	migNames []string,
+	goMigrations map[string]pgm.Migration,
+	opts ExecOptions,
 ) ([]pgm.MigrationResult, error) {
 	results := make([]pgm.MigrationResult, 0)
 
 	for i := len(migNames) - 1; i >= 0; i-- {
-		result, err := RevertMigration(ctx, tx, migTbl, migNames[i])
+		result, err := RevertMigration(ctx, tx, migTbl, migNames[i], goMigrations, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListSchemas получает список пользовательских схем, имена которых соответствуют LIKE шаблону
+func ListSchemas(ctx context.Context, conn Conn, pattern string) ([]string, error) {
+	rows, err := conn.Query(
+		ctx,
+		`SELECT nspname FROM pg_catalog.pg_namespace
+		WHERE nspname LIKE $1
+			AND nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+			AND nspname NOT LIKE 'pg\_temp\_%'
+			AND nspname NOT LIKE 'pg\_toast\_temp\_%'
+		ORDER BY nspname`,
+		pattern,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect schemas rows error: %w", err)
+	}
+
+	return schemas, nil
+}
+
+// QuerySchemas выполняет запрос и возвращает значения его первой колонки как имена схем
+func QuerySchemas(ctx context.Context, conn Conn, query string) ([]string, error) {
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := make([]string, 0)
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) == 0 || values[0] == nil {
+			continue
+		}
+
+		schemas = append(schemas, fmt.Sprint(values[0]))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schemas, nil
+}
+
+// SetSearchPath устанавливает search_path до конца транзакции
+func SetSearchPath(ctx context.Context, tx pgx.Tx, schemas []string) error {
+	path := ""
+	for i, schema := range schemas {
+		if i > 0 {
+			path += ", "
+		}
+		path += pgx.Identifier{schema}.Sanitize()
+	}
+
+	_, err := tx.Exec(ctx, "SELECT set_config('search_path', $1, true)", path)
+
+	return err
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type migrationNameKey struct{}
+
+// WithMigrationName добавляет в контекст имя выполняемой миграции для трассировки запросов
+func WithMigrationName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, migrationNameKey{}, name)
+}
+
+// MigrationName возвращает имя выполняемой миграции из контекста или пустую строку
+func MigrationName(ctx context.Context) string {
+	name, _ := ctx.Value(migrationNameKey{}).(string)
+	return name
+}
+
+// EchoTracer передает каждый запрос в Echo перед его выполнением вместе с именем миграции
+type EchoTracer struct {
+	Echo func(migrationName string, sql string)
+}
+
+func (t *EchoTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	t.Echo(MigrationName(ctx), data.SQL)
+	return ctx
+}
+
+func (t *EchoTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
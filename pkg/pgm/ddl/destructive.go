@@ -0,0 +1,127 @@
+package ddl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+)
+
+var (
+	dropTableRegexp = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.+?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	truncateRegexp  = regexp.MustCompile(`(?is)^TRUNCATE\s+(?:TABLE\s+)?(.+?)(?:\s+(?:RESTART|CONTINUE|CASCADE|RESTRICT)\b.*)?$`)
+	deleteRegexp    = regexp.MustCompile(`(?is)^DELETE\s+FROM\s+(?:ONLY\s+)?(` + QName + `)`)
+	onlyRegexp      = regexp.MustCompile(`(?is)^ONLY\s+`)
+)
+
+// Destruction таблица или столбец, данные которых уничтожает запрос
+type Destruction struct {
+	Table string
+	// Column столбец таблицы. Пустой, если уничтожаются все строки таблицы
+	Column string
+	// Line номер строки sql, с которой начинается запрос
+	Line int
+}
+
+// tableNames разбирает список таблиц запроса
+func tableNames(list string) []string {
+	names := make([]string, 0)
+	for _, name := range sqlsplit.SplitTopLevel(list, ',') {
+		name = strings.TrimSuffix(onlyRegexp.ReplaceAllString(strings.TrimSpace(name), ""), "*")
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Destructive находит в sql запросы, уничтожающие данные: DROP TABLE, TRUNCATE, DELETE и удаление столбцов.
+// DELETE с условием считается удалением всех строк таблицы, поэтому оценка получается сверху.
+func Destructive(sql string) ([]Destruction, error) {
+	statements, err := sqlsplit.Split(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	destructions := make([]Destruction, 0)
+	for _, st := range statements {
+		if st.Meta {
+			continue
+		}
+
+		stmt := strings.TrimSpace(sqlsplit.StripLeadingComments(st.SQL))
+
+		if m := dropTableRegexp.FindStringSubmatch(stmt); m != nil {
+			for _, table := range tableNames(m[1]) {
+				destructions = append(destructions, Destruction{Table: table, Line: st.Line})
+			}
+			continue
+		}
+
+		if m := truncateRegexp.FindStringSubmatch(stmt); m != nil {
+			for _, table := range tableNames(m[1]) {
+				destructions = append(destructions, Destruction{Table: table, Line: st.Line})
+			}
+			continue
+		}
+
+		if m := deleteRegexp.FindStringSubmatch(stmt); m != nil {
+			destructions = append(destructions, Destruction{Table: m[1], Line: st.Line})
+			continue
+		}
+
+		if m := AlterTableRegexp.FindStringSubmatch(stmt); m != nil {
+			for _, action := range sqlsplit.SplitTopLevel(m[3], ',') {
+				if DropConstraintRegexp.MatchString(action) {
+					continue
+				}
+				if c := DropColumnRegexp.FindStringSubmatch(action); c != nil {
+					destructions = append(destructions, Destruction{Table: m[2], Column: c[1], Line: st.Line})
+				}
+			}
+		}
+	}
+
+	return destructions, nil
+}
+
+// AlteredTables возвращает таблицы, которые sql удаляет, очищает, изменяет ALTER TABLE или из которых удаляет строки.
+// Таблицы возвращаются без повторов в порядке появления.
+func AlteredTables(sql string) ([]string, error) {
+	statements, err := sqlsplit.Split(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				tables = append(tables, name)
+			}
+		}
+	}
+
+	for _, st := range statements {
+		if st.Meta {
+			continue
+		}
+
+		stmt := strings.TrimSpace(sqlsplit.StripLeadingComments(st.SQL))
+
+		switch {
+		case dropTableRegexp.MatchString(stmt):
+			add(tableNames(dropTableRegexp.FindStringSubmatch(stmt)[1])...)
+		case truncateRegexp.MatchString(stmt):
+			add(tableNames(truncateRegexp.FindStringSubmatch(stmt)[1])...)
+		case deleteRegexp.MatchString(stmt):
+			add(deleteRegexp.FindStringSubmatch(stmt)[1])
+		case AlterTableRegexp.MatchString(stmt):
+			add(AlterTableRegexp.FindStringSubmatch(stmt)[2])
+		}
+	}
+
+	return tables, nil
+}
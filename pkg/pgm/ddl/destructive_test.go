@@ -0,0 +1,43 @@
+package ddl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Destructive(t *testing.T) {
+	destructions, err := Destructive(`ALTER TABLE public.orders DROP COLUMN note, DROP CONSTRAINT orders_note_check, DROP IF EXISTS "Legacy";
+DROP INDEX public.orders_status;
+DROP TABLE IF EXISTS public.order_items, audit CASCADE;
+TRUNCATE ONLY public.sessions RESTART IDENTITY;
+-- cleanup
+DELETE FROM public.events WHERE kind = 'seed';
+CREATE TABLE public.kept (id bigint);`)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, []Destruction{
+		{Table: "public.orders", Column: "note", Line: 1},
+		{Table: "public.orders", Column: `"Legacy"`, Line: 1},
+		{Table: "public.order_items", Line: 3},
+		{Table: "audit", Line: 3},
+		{Table: "public.sessions", Line: 4},
+		{Table: "public.events", Line: 5},
+	}, destructions)
+}
+
+func Test_AlteredTables(t *testing.T) {
+	tables, err := AlteredTables(`ALTER TABLE public.orders ALTER COLUMN total TYPE integer;
+DROP INDEX public.orders_status;
+ALTER TABLE IF EXISTS ONLY public.orders DROP COLUMN note;
+DROP TABLE public.order_items, audit;
+DELETE FROM ONLY public.events WHERE kind = 'seed';
+TRUNCATE public.sessions;`)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, []string{"public.orders", "public.order_items", "audit", "public.events", "public.sessions"}, tables)
+}
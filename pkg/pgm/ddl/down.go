@@ -0,0 +1,291 @@
+// Package ddl генерирует down sql для типовых обратимых DDL запросов up sql миграции
+// и находит запросы, уничтожающие данные
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+)
+
+// TodoMarker отмечает в down sql место, которое нужно дописать или проверить вручную
+const TodoMarker = "TODO(pgm)"
+
+var (
+	createIndexRegexp = regexp.MustCompile(
+		`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(IF\s+NOT\s+EXISTS\s+)?(` + Ident + `)\s+ON\s+(?:ONLY\s+)?(` + QName + `)`,
+	)
+	createViewRegexp = regexp.MustCompile(
+		`(?is)^CREATE\s+(OR\s+REPLACE\s+)?(?:(?:TEMP|TEMPORARY)\s+)?(?:RECURSIVE\s+)?(MATERIALIZED\s+)?VIEW\s+(IF\s+NOT\s+EXISTS\s+)?(` + QName + `)`,
+	)
+	createRoutineRegexp = regexp.MustCompile(`(?is)^CREATE\s+(OR\s+REPLACE\s+)?(FUNCTION|PROCEDURE)\s+(` + QName + `)\s*\(`)
+	createSchemaRegexp  = regexp.MustCompile(`(?is)^CREATE\s+SCHEMA\s+(IF\s+NOT\s+EXISTS\s+)?(` + Ident + `)`)
+	createSeqRegexp     = regexp.MustCompile(
+		`(?is)^CREATE\s+(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?SEQUENCE\s+(IF\s+NOT\s+EXISTS\s+)?(` + QName + `)`,
+	)
+	createTypeRegexp      = regexp.MustCompile(`(?is)^CREATE\s+TYPE\s+(` + QName + `)`)
+	createExtensionRegexp = regexp.MustCompile(`(?is)^CREATE\s+EXTENSION\s+(IF\s+NOT\s+EXISTS\s+)?(` + Ident + `)`)
+	createTriggerRegexp   = regexp.MustCompile(
+		`(?is)^CREATE\s+(OR\s+REPLACE\s+)?(?:CONSTRAINT\s+)?TRIGGER\s+(` + Ident + `)\s.*?\bON\s+(` + QName + `)`,
+	)
+	renameRegexp = regexp.MustCompile(
+		`(?is)^ALTER\s+(TABLE|INDEX|VIEW|MATERIALIZED\s+VIEW|SEQUENCE|TYPE|SCHEMA)\s+(IF\s+EXISTS\s+)?(` + QName + `)\s+RENAME\s+TO\s+(` + Ident + `)\s*$`,
+	)
+	addConstraintRegexp    = regexp.MustCompile(`(?is)^ADD\s+CONSTRAINT\s+(` + Ident + `)\s`)
+	addUnnamedRegexp       = regexp.MustCompile(`(?is)^ADD\s+(PRIMARY|UNIQUE|CHECK|FOREIGN|EXCLUDE)\b`)
+	renameColumnRegexp     = regexp.MustCompile(`(?is)^RENAME\s+(?:COLUMN\s+)?(` + Ident + `)\s+TO\s+(` + Ident + `)$`)
+	renameConstraintRegexp = regexp.MustCompile(`(?is)^RENAME\s+CONSTRAINT\s+(` + Ident + `)\s+TO\s+(` + Ident + `)$`)
+	defaultRegexp          = regexp.MustCompile(`(?is)^(.*?)\s*(?:\bDEFAULT\b|=).*$`)
+	spaceRegexp            = regexp.MustCompile(`\s+`)
+)
+
+// Result результат генерации down sql
+type Result struct {
+	SQL string
+	// Todos количество мест, отмеченных TodoMarker, которые нужно дописать или проверить вручную
+	Todos int
+}
+
+// inverse обратный запрос. note - причина, по которой запрос нужно проверить вручную
+type inverse struct {
+	sql  string
+	note string
+}
+
+// GenerateDown генерирует down sql для up sql. Обратные запросы идут в обратном порядке.
+// Для запросов, которые не удалось обратить, в down sql добавляется закомментированный запрос с TodoMarker.
+func GenerateDown(upSql string) (*Result, error) {
+	statements, err := sqlsplit.Split(upSql)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(Result)
+	blocks := make([]string, 0, len(statements))
+
+	for i := len(statements) - 1; i >= 0; i-- {
+		st := statements[i]
+		inv, ok := invert(st)
+
+		switch {
+		case !ok:
+			result.Todos++
+			blocks = append(blocks, fmt.Sprintf(
+				"-- %s: write down sql for the statement at line %d of up sql\n%s",
+				TodoMarker,
+				st.Line,
+				commentOut(st.SQL),
+			))
+		case inv.note != "":
+			result.Todos++
+			blocks = append(blocks, fmt.Sprintf("-- %s: %s (line %d of up sql)\n%s;", TodoMarker, inv.note, st.Line, inv.sql))
+		default:
+			blocks = append(blocks, inv.sql+";")
+		}
+	}
+
+	result.SQL = strings.Join(blocks, "\n")
+	if result.SQL != "" {
+		result.SQL += "\n"
+	}
+
+	return result, nil
+}
+
+// commentOut закомментирует каждую строку запроса
+func commentOut(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, line := range lines {
+		lines[i] = "-- " + strings.TrimRight(line, "\r")
+	}
+	return strings.Join(lines, "\n") + ";"
+}
+
+// ifExists возвращает IF EXISTS, если исходный запрос содержал IF NOT EXISTS
+func ifExists(ifNotExists string) string {
+	if ifNotExists != "" {
+		return "IF EXISTS "
+	}
+	return ""
+}
+
+// splitName разбивает квалифицированное имя на префикс со схемой и имя объекта
+func splitName(name string) (string, string) {
+	parts := sqlsplit.SplitTopLevel(name, '.')
+	if len(parts) == 1 {
+		return "", name
+	}
+	return strings.Join(parts[:len(parts)-1], ".") + ".", parts[len(parts)-1]
+}
+
+// routineArgs возвращает типы аргументов функции, список которых начинается после открывающей скобки в start
+func routineArgs(sql string, start int) (string, bool) {
+	depth := 1
+	var quote byte
+	for i := start; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			args := strings.TrimSpace(sql[start:i])
+			if args == "" {
+				return "", true
+			}
+
+			parts := sqlsplit.SplitTopLevel(args, ',')
+			for j, arg := range parts {
+				if m := defaultRegexp.FindStringSubmatch(arg); m != nil {
+					arg = m[1]
+				}
+				parts[j] = spaceRegexp.ReplaceAllString(strings.TrimSpace(arg), " ")
+			}
+			return strings.Join(parts, ", "), true
+		}
+	}
+
+	return "", false
+}
+
+// invert возвращает обратный запрос для DDL запроса
+func invert(st sqlsplit.Statement) (inverse, bool) {
+	if st.Meta {
+		return inverse{}, false
+	}
+
+	sql := strings.TrimSpace(sqlsplit.StripLeadingComments(st.SQL))
+
+	if m := CreateTableRegexp.FindStringSubmatch(sql); m != nil {
+		return inverse{sql: "DROP TABLE " + ifExists(m[1]) + m[2]}, true
+	}
+
+	if m := createIndexRegexp.FindStringSubmatch(sql); m != nil {
+		if strings.EqualFold(m[3], "CONCURRENTLY") {
+			return inverse{}, false
+		}
+
+		// down sql выполняется в транзакции миграции, где CONCURRENTLY недопустим,
+		// поэтому индекс, созданный с CONCURRENTLY, удаляется обычным DROP INDEX
+		schema, _ := splitName(m[4])
+		return inverse{sql: "DROP INDEX " + ifExists(m[2]) + schema + m[3]}, true
+	}
+
+	if m := createViewRegexp.FindStringSubmatch(sql); m != nil {
+		kind := "VIEW"
+		if m[2] != "" {
+			kind = "MATERIALIZED VIEW"
+		}
+		return orReplace(m[1], inverse{sql: "DROP " + kind + " " + ifExists(m[3]) + m[4]}), true
+	}
+
+	if m := createRoutineRegexp.FindStringSubmatchIndex(sql); m != nil {
+		args, ok := routineArgs(sql, m[1])
+		if !ok {
+			return inverse{}, false
+		}
+		replace := ""
+		if m[2] >= 0 {
+			replace = sql[m[2]:m[3]]
+		}
+		kind := strings.ToUpper(sql[m[4]:m[5]])
+		name := sql[m[6]:m[7]]
+		return orReplace(replace, inverse{sql: fmt.Sprintf("DROP %s %s(%s)", kind, name, args)}), true
+	}
+
+	if m := createSchemaRegexp.FindStringSubmatch(sql); m != nil {
+		if strings.EqualFold(m[2], "AUTHORIZATION") {
+			return inverse{}, false
+		}
+		return inverse{sql: "DROP SCHEMA " + ifExists(m[1]) + m[2]}, true
+	}
+
+	if m := createSeqRegexp.FindStringSubmatch(sql); m != nil {
+		return inverse{sql: "DROP SEQUENCE " + ifExists(m[1]) + m[2]}, true
+	}
+
+	if m := createTypeRegexp.FindStringSubmatch(sql); m != nil {
+		return inverse{sql: "DROP TYPE " + m[1]}, true
+	}
+
+	if m := createExtensionRegexp.FindStringSubmatch(sql); m != nil {
+		return inverse{sql: "DROP EXTENSION " + ifExists(m[1]) + m[2]}, true
+	}
+
+	if m := createTriggerRegexp.FindStringSubmatch(sql); m != nil {
+		return orReplace(m[1], inverse{sql: "DROP TRIGGER " + m[2] + " ON " + m[3]}), true
+	}
+
+	if m := renameRegexp.FindStringSubmatch(sql); m != nil {
+		kind := strings.ToUpper(spaceRegexp.ReplaceAllString(m[1], " "))
+		schema, oldName := splitName(m[3])
+		if kind == "SCHEMA" {
+			schema = ""
+		}
+		return inverse{sql: fmt.Sprintf("ALTER %s %s%s RENAME TO %s", kind, schema, m[4], oldName)}, true
+	}
+
+	if m := AlterTableRegexp.FindStringSubmatch(sql); m != nil {
+		return invertAlterTable(m[1], m[2], m[3])
+	}
+
+	return inverse{}, false
+}
+
+// orReplace отмечает обратный запрос для CREATE OR REPLACE: объект мог существовать до миграции
+func orReplace(replace string, inv inverse) inverse {
+	if replace != "" {
+		inv.note = "restore the previous definition if the object existed before this migration"
+	}
+	return inv
+}
+
+// invertAlterTable обращает действия ALTER TABLE в обратном порядке
+func invertAlterTable(ifExistsClause string, table string, actions string) (inverse, bool) {
+	parts := sqlsplit.SplitTopLevel(actions, ',')
+	inverted := make([]string, 0, len(parts))
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		action := parts[i]
+
+		if addUnnamedRegexp.MatchString(action) {
+			return inverse{}, false
+		}
+
+		if m := addConstraintRegexp.FindStringSubmatch(action + " "); m != nil {
+			inverted = append(inverted, "DROP CONSTRAINT "+m[1])
+			continue
+		}
+
+		if m := AddColumnRegexp.FindStringSubmatch(action + " "); m != nil {
+			inverted = append(inverted, "DROP COLUMN "+ifExists(m[1])+m[2])
+			continue
+		}
+
+		if m := renameConstraintRegexp.FindStringSubmatch(action); m != nil {
+			inverted = append(inverted, fmt.Sprintf("RENAME CONSTRAINT %s TO %s", m[2], m[1]))
+			continue
+		}
+
+		if m := renameColumnRegexp.FindStringSubmatch(action); m != nil && !strings.EqualFold(m[1], "TO") {
+			inverted = append(inverted, fmt.Sprintf("RENAME COLUMN %s TO %s", m[2], m[1]))
+			continue
+		}
+
+		return inverse{}, false
+	}
+
+	return inverse{sql: "ALTER TABLE " + ifExists(ifExistsClause) + table + " " + strings.Join(inverted, ", ")}, true
+}
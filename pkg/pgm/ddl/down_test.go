@@ -0,0 +1,72 @@
+package ddl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GenerateDown(t *testing.T) {
+	t.Run("should invert common ddl in reverse order", func(t *testing.T) {
+		up := `CREATE SCHEMA IF NOT EXISTS billing;
+CREATE TABLE billing.invoices (id BIGSERIAL PRIMARY KEY, total NUMERIC(10, 2));
+CREATE UNIQUE INDEX invoices_total_idx ON billing.invoices (total);
+CREATE SEQUENCE billing.invoice_numbers;
+CREATE TYPE billing.status AS ENUM ('new', 'paid');
+CREATE MATERIALIZED VIEW billing.totals AS SELECT sum(total) FROM billing.invoices;
+CREATE FUNCTION billing.add(a INT, b NUMERIC(10, 2) DEFAULT 0, VARIADIC c INT[] = '{}') RETURNS INT AS $$ SELECT 1; $$ LANGUAGE sql;
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+CREATE TRIGGER invoices_audit AFTER INSERT ON billing.invoices FOR EACH ROW EXECUTE FUNCTION audit();
+ALTER TABLE billing.invoices ADD COLUMN status billing.status, ADD CONSTRAINT invoices_total_check CHECK (total > 0);
+ALTER TABLE billing.invoices RENAME COLUMN total TO amount;
+ALTER TABLE billing.invoices RENAME TO bills;
+`
+		result, err := GenerateDown(up)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Todos)
+		assert.Equal(t, `ALTER TABLE billing.bills RENAME TO invoices;
+ALTER TABLE billing.invoices RENAME COLUMN amount TO total;
+ALTER TABLE billing.invoices DROP CONSTRAINT invoices_total_check, DROP COLUMN status;
+DROP TRIGGER invoices_audit ON billing.invoices;
+DROP EXTENSION IF EXISTS pgcrypto;
+DROP FUNCTION billing.add(a INT, b NUMERIC(10, 2), VARIADIC c INT[]);
+DROP MATERIALIZED VIEW billing.totals;
+DROP TYPE billing.status;
+DROP SEQUENCE billing.invoice_numbers;
+DROP INDEX billing.invoices_total_idx;
+DROP TABLE billing.invoices;
+DROP SCHEMA IF EXISTS billing;
+`, result.SQL)
+	})
+
+	t.Run("should mark statements that can't be inverted", func(t *testing.T) {
+		up := "CREATE TABLE a (id INT);\n-- seed\nINSERT INTO a VALUES (1);\nCREATE INDEX ON a (id);\nALTER TABLE a ADD PRIMARY KEY (id);"
+		result, err := GenerateDown(up)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Todos)
+		assert.Equal(t, `-- TODO(pgm): write down sql for the statement at line 5 of up sql
+-- ALTER TABLE a ADD PRIMARY KEY (id);
+-- TODO(pgm): write down sql for the statement at line 4 of up sql
+-- CREATE INDEX ON a (id);
+-- TODO(pgm): write down sql for the statement at line 2 of up sql
+-- -- seed
+-- INSERT INTO a VALUES (1);
+DROP TABLE a;
+`, result.SQL)
+	})
+
+	t.Run("should drop concurrently created index without concurrently", func(t *testing.T) {
+		result, err := GenerateDown("CREATE INDEX CONCURRENTLY IF NOT EXISTS users_email_idx ON app.users (email);")
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Todos)
+		assert.Equal(t, "DROP INDEX IF EXISTS app.users_email_idx;\n", result.SQL)
+	})
+
+	t.Run("should ask to restore replaced definitions", func(t *testing.T) {
+		result, err := GenerateDown("CREATE OR REPLACE VIEW v AS SELECT 1;")
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Todos)
+		assert.Equal(t, "-- TODO(pgm): restore the previous definition if the object existed before this migration (line 1 of up sql)\nDROP VIEW v;\n", result.SQL)
+	})
+}
@@ -0,0 +1,26 @@
+package ddl
+
+import "regexp"
+
+const (
+	// Ident регулярное выражение идентификатора postgres: имени в кавычках или без них
+	Ident = `(?:"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)`
+	// QName регулярное выражение имени объекта, возможно со схемой и базой данных
+	QName = Ident + `(?:\.` + Ident + `){0,2}`
+)
+
+var (
+	// CreateTableRegexp находит CREATE TABLE. Группы: IF NOT EXISTS, имя таблицы
+	CreateTableRegexp = regexp.MustCompile(
+		`(?is)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(IF\s+NOT\s+EXISTS\s+)?(` + QName + `)`,
+	)
+	// AlterTableRegexp находит ALTER TABLE. Группы: IF EXISTS, имя таблицы, список действий
+	AlterTableRegexp = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + QName + `)\s+(.+)$`)
+	// AddColumnRegexp находит действие ADD COLUMN в ALTER TABLE. Группы: IF NOT EXISTS, имя столбца, определение столбца
+	AddColumnRegexp = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(IF\s+NOT\s+EXISTS\s+)?(` + Ident + `)\s+(.*)$`)
+	// DropColumnRegexp находит действие DROP COLUMN в ALTER TABLE. Группа: имя столбца.
+	// Совпадает и с DROP CONSTRAINT, поэтому его нужно исключать через DropConstraintRegexp
+	DropColumnRegexp = regexp.MustCompile(`(?is)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?(` + Ident + `)`)
+	// DropConstraintRegexp находит действие DROP CONSTRAINT в ALTER TABLE
+	DropConstraintRegexp = regexp.MustCompile(`(?is)^DROP\s+CONSTRAINT\b`)
+)
@@ -0,0 +1,296 @@
+package pgm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrConflict миграции в базе данных не совпадают с миграциями в файловой системе
+	ErrConflict = errors.New("migrations conflict")
+	// ErrLockTimeout не удалось дождаться блокировки таблицы миграций
+	ErrLockTimeout = errors.New("migrations table lock timeout")
+	// ErrNoMigrations в базе данных нет примененных миграций
+	ErrNoMigrations = errors.New("migrations not found")
+	// ErrConnection не удалось подключиться к базе данных
+	ErrConnection = errors.New("database connection error")
+	// ErrUnresolvedPlaceholder в sql миграции есть плейсхолдер без значения
+	ErrUnresolvedPlaceholder = errors.New("unresolved placeholder")
+	// ErrSchemaDrift схема базы данных не совпадает с сохраненным снимком схемы или с другой базой данных
+	ErrSchemaDrift = errors.New("schema drift")
+	// ErrLint в sql миграций найдены нарушения правил с важностью error
+	ErrLint = errors.New("lint errors")
+	// ErrRevertRefused откат миграций запрещен защитой базы данных, ограничением, оценкой потери данных или не подтвержден
+	ErrRevertRefused = errors.New("revert refused")
+	// ErrValidation неверные параметры мигратора
+	ErrValidation = errors.New("validation error")
+)
+
+// ValidationError неверный параметр мигратора, например приоритет или имя таблицы миграций
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ConnectionError ошибка подключения к базе данных
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectionError) Is(target error) bool {
+	return target == ErrConnection
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// ConflictError миграция в базе данных не совпадает с миграцией в файловой системе на той же позиции
+type ConflictError struct {
+	Applied string
+	Source  string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("migrations are not the same in file system and db. %s != %s", e.Applied, e.Source)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// PartialSquashError в базе данных применена только часть миграций, объединенных в базовую миграцию Baseline
+type PartialSquashError struct {
+	Baseline string
+	Applied  []string
+}
+
+func (e *PartialSquashError) Error() string {
+	return fmt.Sprintf(
+		"only part of migrations squashed into %s is applied to db: %s. apply the remaining squashed migrations before migrating to the baseline",
+		e.Baseline,
+		strings.Join(e.Applied, ", "),
+	)
+}
+
+func (e *PartialSquashError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// LockTimeoutError таблица миграций заблокирована другим процессом дольше допустимого
+type LockTimeoutError struct {
+	Table string
+	Err   error
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("lock migrations table %s timeout: %v", e.Table, e.Err)
+}
+
+func (e *LockTimeoutError) Is(target error) bool {
+	return target == ErrLockTimeout
+}
+
+func (e *LockTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// UnresolvedPlaceholderError в sql есть плейсхолдеры ${name}, для которых не задано значение
+type UnresolvedPlaceholderError struct {
+	Names []string
+}
+
+func (e *UnresolvedPlaceholderError) Error() string {
+	return fmt.Sprintf("unresolved placeholders: %s", strings.Join(e.Names, ", "))
+}
+
+func (e *UnresolvedPlaceholderError) Is(target error) bool {
+	return target == ErrUnresolvedPlaceholder
+}
+
+// SchemaDriftError снимок схемы File не совпадает со схемой базы данных начиная со строки Line
+type SchemaDriftError struct {
+	File string
+	Line int
+	// Expected строка сохраненного снимка, Actual строка снимка базы данных. Пустые, если снимок короче.
+	Expected string
+	Actual   string
+}
+
+func (e *SchemaDriftError) Error() string {
+	line := func(s string) string {
+		if s == "" {
+			return "<end of file>"
+		}
+		return s
+	}
+
+	return fmt.Sprintf(
+		"schema snapshot %s doesn't match the database at line %d\n  - %s\n  + %s",
+		e.File, e.Line, line(e.Expected), line(e.Actual),
+	)
+}
+
+func (e *SchemaDriftError) Is(target error) bool {
+	return target == ErrSchemaDrift
+}
+
+// SchemaDiffError схемы исходной и целевой баз данных различаются
+type SchemaDiffError struct {
+	Differences int
+}
+
+func (e *SchemaDiffError) Error() string {
+	return fmt.Sprintf("schemas differ: %d differences", e.Differences)
+}
+
+func (e *SchemaDiffError) Is(target error) bool {
+	return target == ErrSchemaDrift
+}
+
+// IncompleteDownError откат миграций Names не возвращает схему к состоянию до их применения
+type IncompleteDownError struct {
+	Names []string
+}
+
+func (e *IncompleteDownError) Error() string {
+	return fmt.Sprintf("down doesn't restore the schema for migrations: %s", strings.Join(e.Names, ", "))
+}
+
+func (e *IncompleteDownError) Is(target error) bool {
+	return target == ErrSchemaDrift
+}
+
+// LintError проверка миграций нашла Errors нарушений с важностью error и Warnings предупреждений
+type LintError struct {
+	Errors   int
+	Warnings int
+}
+
+func (e *LintError) Error() string {
+	return fmt.Sprintf("lint found %d errors and %d warnings", e.Errors, e.Warnings)
+}
+
+func (e *LintError) Is(target error) bool {
+	return target == ErrLint
+}
+
+// RevertRefusedError откат миграций Names не выполнен по причине Reason
+type RevertRefusedError struct {
+	Names  []string
+	Reason string
+}
+
+func (e *RevertRefusedError) Error() string {
+	return fmt.Sprintf("revert of %d migrations refused: %s. migrations: %s", len(e.Names), e.Reason, strings.Join(e.Names, ", "))
+}
+
+func (e *RevertRefusedError) Is(target error) bool {
+	return target == ErrRevertRefused
+}
+
+// DataLossError откат миграций уничтожит больше строк, чем MaxLostRows
+type DataLossError struct {
+	Losses      []DataLoss
+	MaxLostRows int64
+}
+
+func (e *DataLossError) Error() string {
+	rows, bytes := TotalDataLoss(e.Losses)
+	objects := make([]string, 0, len(e.Losses))
+	for _, l := range e.Losses {
+		objects = append(objects, fmt.Sprintf("%s %s ~%d rows", l.Migration, l.Object(), l.Rows))
+	}
+
+	return fmt.Sprintf(
+		"revert would destroy about %d rows (%s), max lost rows is %d, pass force data loss to revert. data loss: %s",
+		rows, FormatBytes(bytes), e.MaxLostRows, strings.Join(objects, ", "),
+	)
+}
+
+func (e *DataLossError) Is(target error) bool {
+	return target == ErrRevertRefused
+}
+
+// MigrationError ошибка применения или отката миграции
+type MigrationError struct {
+	Name string
+	// Action действие, при котором произошла ошибка
+	Action PlanAction
+	// SQLState код ошибки postgres, если ошибка пришла от сервера
+	SQLState string
+	// File файл миграции, в котором находится ошибочный sql, если его удалось определить
+	File string
+	// Line и Column положение ошибки: в файле, если задан File, иначе в выполненном sql
+	Line   int
+	Column int
+	// Snippet строка sql с указателем на место ошибки
+	Snippet    string
+	Detail     string
+	Hint       string
+	Schema     string
+	Table      string
+	Constraint string
+	Err        error
+}
+
+func (e *MigrationError) Error() string {
+	var b strings.Builder
+
+	if e.SQLState != "" {
+		fmt.Fprintf(&b, "%s %s migration error (SQLSTATE %s): %v", e.Action, e.Name, e.SQLState, e.Err)
+	} else {
+		fmt.Fprintf(&b, "%s %s migration error: %v", e.Action, e.Name, e.Err)
+	}
+
+	switch {
+	case e.File != "" && e.Line > 0:
+		fmt.Fprintf(&b, "\n  at %s:%d:%d", e.File, e.Line, e.Column)
+	case e.File != "":
+		fmt.Fprintf(&b, "\n  at %s", e.File)
+	case e.Line > 0:
+		fmt.Fprintf(&b, "\n  at line %d, column %d", e.Line, e.Column)
+	}
+
+	if e.Detail != "" {
+		fmt.Fprintf(&b, "\n  detail: %s", e.Detail)
+	}
+
+	if e.Hint != "" {
+		fmt.Fprintf(&b, "\n  hint: %s", e.Hint)
+	}
+
+	if e.Table != "" {
+		table := e.Table
+		if e.Schema != "" {
+			table = e.Schema + "." + e.Table
+		}
+		fmt.Fprintf(&b, "\n  table: %s", table)
+	}
+
+	if e.Constraint != "" {
+		fmt.Fprintf(&b, "\n  constraint: %s", e.Constraint)
+	}
+
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, "\n%s", e.Snippet)
+	}
+
+	return b.String()
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
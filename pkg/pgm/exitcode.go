@@ -0,0 +1,65 @@
+package pgm
+
+import (
+	"errors"
+)
+
+// Коды завершения утилиты. Значения документированы в README и не должны меняться.
+const (
+	EXIT_OK = 0
+	// EXIT_ERROR ошибка, не относящаяся к остальным классам
+	EXIT_ERROR = 1
+	// EXIT_VALIDATION неверные параметры командной строки или мигратора
+	EXIT_VALIDATION = 2
+	// EXIT_CONNECTION не удалось подключиться к базе данных
+	EXIT_CONNECTION = 3
+	// EXIT_LOCK_TIMEOUT не удалось дождаться блокировки таблицы миграций
+	EXIT_LOCK_TIMEOUT = 4
+	// EXIT_CONFLICT миграции в базе данных и файловой системе не совпадают
+	EXIT_CONFLICT = 5
+	// EXIT_MIGRATION ошибка выполнения миграции или колбэка
+	EXIT_MIGRATION = 6
+	// EXIT_NOTHING_TO_DO команде down нечего откатывать
+	EXIT_NOTHING_TO_DO = 7
+	// EXIT_SCHEMA_DRIFT схема базы данных не совпадает с сохраненным снимком схемы или с другой базой данных
+	EXIT_SCHEMA_DRIFT = 8
+	// EXIT_LINT проверка миграций нашла ошибки
+	EXIT_LINT = 9
+	// EXIT_REVERT_REFUSED откат миграций запрещен защитой базы данных, ограничением, оценкой потери данных или не подтвержден
+	EXIT_REVERT_REFUSED = 10
+)
+
+// ExitCode возвращает код завершения для ошибки команды.
+// Если ошибка объединяет несколько ошибок (например, TargetsError), выбирается первый
+// подходящий класс в порядке: подключение, блокировка, конфликт, миграция, нечего откатывать, расхождение схемы, ошибки проверки миграций, отказ в откате, неверные параметры.
+func ExitCode(err error) int {
+	if err == nil {
+		return EXIT_OK
+	}
+
+	var migErr *MigrationError
+	var hookErr *HookError
+
+	switch {
+	case errors.Is(err, ErrConnection):
+		return EXIT_CONNECTION
+	case errors.Is(err, ErrLockTimeout):
+		return EXIT_LOCK_TIMEOUT
+	case errors.Is(err, ErrConflict):
+		return EXIT_CONFLICT
+	case errors.As(err, &migErr), errors.As(err, &hookErr):
+		return EXIT_MIGRATION
+	case errors.Is(err, ErrNoMigrations):
+		return EXIT_NOTHING_TO_DO
+	case errors.Is(err, ErrSchemaDrift):
+		return EXIT_SCHEMA_DRIFT
+	case errors.Is(err, ErrLint):
+		return EXIT_LINT
+	case errors.Is(err, ErrRevertRefused):
+		return EXIT_REVERT_REFUSED
+	case errors.Is(err, ErrValidation):
+		return EXIT_VALIDATION
+	default:
+		return EXIT_ERROR
+	}
+}
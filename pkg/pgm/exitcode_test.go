@@ -0,0 +1,36 @@
+package pgm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExitCode(t *testing.T) {
+	assert.Equal(t, EXIT_OK, ExitCode(nil))
+	assert.Equal(t, EXIT_ERROR, ExitCode(errors.New("unknown")))
+	assert.Equal(t, EXIT_CONNECTION, ExitCode(fmt.Errorf("database connection errors: %w", &ConnectionError{Err: errors.New("refused")})))
+	assert.Equal(t, EXIT_LOCK_TIMEOUT, ExitCode(&LockTimeoutError{Table: "public.migrations", Err: errors.New("timeout")}))
+	assert.Equal(t, EXIT_CONFLICT, ExitCode(&ConflictError{Applied: "1_a", Source: "1_b"}))
+	assert.Equal(t, EXIT_CONFLICT, ExitCode(&PartialSquashError{Baseline: "3_baseline", Applied: []string{"1_a"}}))
+	assert.Equal(t, EXIT_MIGRATION, ExitCode(&MigrationError{Name: "1_a", Action: APPLY, Err: errors.New("syntax error")}))
+	assert.Equal(t, EXIT_MIGRATION, ExitCode(&HookError{Event: AFTER_MIGRATE, Err: errors.New("syntax error")}))
+	assert.Equal(t, EXIT_NOTHING_TO_DO, ExitCode(ErrNoMigrations))
+	assert.Equal(t, EXIT_SCHEMA_DRIFT, ExitCode(&SchemaDriftError{File: "schema.sql", Line: 3}))
+	assert.Equal(t, EXIT_SCHEMA_DRIFT, ExitCode(&SchemaDiffError{Differences: 2}))
+	assert.Equal(t, EXIT_SCHEMA_DRIFT, ExitCode(&IncompleteDownError{Names: []string{"1_a"}}))
+	assert.Equal(t, EXIT_LINT, ExitCode(&LintError{Errors: 1, Warnings: 2}))
+	assert.Equal(t, EXIT_VALIDATION, ExitCode(&ValidationError{Message: "invalid priority"}))
+	assert.Equal(t, EXIT_REVERT_REFUSED, ExitCode(&RevertRefusedError{Names: []string{"2_b"}, Reason: "database is protected"}))
+	assert.Equal(t, EXIT_REVERT_REFUSED, ExitCode(&DataLossError{Losses: []DataLoss{{Migration: "2_b", Table: "orders", Rows: 10}}, MaxLostRows: 1}))
+
+	t.Run("should classify targets error by failed targets", func(t *testing.T) {
+		err := CheckTargets([]TargetResult{
+			{Target: "eu", Status: TARGET_FAILED, Err: &MigrationError{Name: "1_a", Action: APPLY, Err: errors.New("boom")}},
+			{Target: "us", Status: TARGET_FAILED, Err: &LockTimeoutError{Table: "public.migrations", Err: errors.New("timeout")}},
+		})
+		assert.Equal(t, EXIT_LOCK_TIMEOUT, ExitCode(err))
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 )
 
 type Flags struct {
@@ -14,6 +16,43 @@ type Flags struct {
 	MigrationsTableSchema string
 	MigrationsTable       string
 	ConnectionString      string
+	Recursive             bool
+	Format                string
+	FromUp                string
+	LockTimeout           time.Duration
+	SplitStatements       bool
+	StatementTimeout      time.Duration
+	Vars                  map[string]string
+	Tenants               string
+	TenantsQuery          string
+	TenantsPattern        string
+	Parallel              int
+	OnError               string
+	FailFast              bool
+	ConnectionStrings     []string
+	ConnectionStringsFile string
+	Canary                bool
+	Output                string
+	LogLevel              string
+	Quiet                 bool
+	Verbose               bool
+	EchoSql               bool
+	Schemas               string
+	SchemaFile            string
+	Check                 bool
+	Source                string
+	Target                string
+	Before                string
+	ArchiveDir            string
+	LintRules             []string
+	Pending               bool
+	Protected             bool
+	AllowRevert           bool
+	MaxReverts            int
+	MaxLostRows           int64
+	ForceDataLoss         bool
+	BackupOnRevert        bool
+	OlderThan             time.Duration
 }
 
 func (f *Flags) ToMigratorOptions() MigratorOptions {
@@ -25,15 +64,156 @@ func (f *Flags) ToMigratorOptions() MigratorOptions {
 		MigrationsTableSchema: f.MigrationsTableSchema,
 		MigrationsTable:       f.MigrationsTable,
 		ConnectionString:      f.ConnectionString,
+		Recursive:             f.Recursive,
+		Format:                MigrationFormat(f.Format),
+		FromUp:                f.FromUp,
+		LockTimeout:           f.LockTimeout,
+		SplitStatements:       f.SplitStatements,
+		StatementTimeout:      f.StatementTimeout,
+		Vars:                  f.Vars,
+		Tenants:               f.tenants(),
+		TenantsQuery:          f.TenantsQuery,
+		TenantsPattern:        f.TenantsPattern,
+		Parallel:              f.Parallel,
+		OnError:               f.onError(),
+		ConnectionStrings:     f.ConnectionStrings,
+		ConnectionStringsFile: f.ConnectionStringsFile,
+		Canary:                f.Canary,
+		Output:                OutputFormat(f.Output),
+		EchoSql:               f.EchoSql,
+		Schemas:               f.schemas(),
+		SchemaFile:            f.SchemaFile,
+		Check:                 f.Check,
+		Source:                f.Source,
+		Target:                f.Target,
+		Before:                f.Before,
+		ArchiveDir:            f.ArchiveDir,
+		LintRules:             f.lintRules(),
+		Pending:               f.Pending,
+		Protected:             f.Protected,
+		AllowRevert:           f.AllowRevert,
+		MaxReverts:            f.MaxReverts,
+		MaxLostRows:           f.MaxLostRows,
+		ForceDataLoss:         f.ForceDataLoss,
+		BackupOnRevert:        f.BackupOnRevert,
+		OlderThan:             f.OlderThan,
 	}
 }
 
-func (f *Flags) Validate() error {
-	if f.MigrationsDir == "" {
-		return errors.New("migrations dir is required")
+// EffectiveLogLevel возвращает уровень логирования с учетом флагов quiet и verbose
+func (f *Flags) EffectiveLogLevel() string {
+	switch {
+	case f.Quiet:
+		return "error"
+	case f.Verbose:
+		return "debug"
+	case f.LogLevel == "":
+		return "info"
+	default:
+		return f.LogLevel
+	}
+}
+
+// onError возвращает политику обработки ошибок с учетом флага failFast
+func (f *Flags) onError() ErrorPolicy {
+	if f.FailFast {
+		return FAIL_FAST
+	}
+	return ErrorPolicy(f.OnError)
+}
+
+// tenants разбирает список схем тенантов, перечисленных через запятую
+func (f *Flags) tenants() []string {
+	tenants := make([]string, 0)
+	for _, t := range strings.Split(f.Tenants, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tenants = append(tenants, t)
+		}
+	}
+	return tenants
+}
+
+// schemas разбирает список схем снимка схемы, перечисленных через запятую
+func (f *Flags) schemas() []string {
+	schemas := make([]string, 0)
+	for _, s := range strings.Split(f.Schemas, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			schemas = append(schemas, s)
+		}
+	}
+	return schemas
+}
+
+// lintRules разбирает важность правил проверки миграций в формате rule=severity
+func (f *Flags) lintRules() map[string]string {
+	rules := make(map[string]string, len(f.LintRules))
+	for _, r := range f.LintRules {
+		name, severity, _ := strings.Cut(r, "=")
+		rules[strings.TrimSpace(name)] = strings.TrimSpace(severity)
+	}
+	return rules
+}
+
+// validateSchemas проверяет имена схем снимка или сравнения схем
+func (f *Flags) validateSchemas() error {
+	for _, s := range f.schemas() {
+		if match, err := regexp.MatchString(`^[a-zA-Z0-9_]+$`, s); err == nil && !match {
+			return fmt.Errorf("schema \"%s\" might contain only letters, numbers and \"_\" symbol", s)
+		}
+	}
+	return nil
+}
+
+// validateTenants проверяет параметры режима тенантов
+func (f *Flags) validateTenants() error {
+	sources := 0
+	for _, s := range []string{f.Tenants, f.TenantsQuery, f.TenantsPattern} {
+		if s != "" {
+			sources++
+		}
 	}
 
+	if sources > 1 {
+		return errors.New("only one of tenants, tenants query or tenants pattern might be specified")
+	}
+
+	for _, t := range f.tenants() {
+		if match, err := regexp.MatchString(`^[a-zA-Z0-9_]+$`, t); err == nil && !match {
+			return fmt.Errorf("tenant schema \"%s\" might contain only letters, numbers and \"_\" symbol", t)
+		}
+	}
+
+	return f.validateParallel()
+}
+
+// validateParallel проверяет параметры параллельной миграции нескольких целей
+func (f *Flags) validateParallel() error {
+	if f.Parallel < 1 {
+		return errors.New("parallel must be greater than 0")
+	}
+
+	switch ErrorPolicy(f.OnError) {
+	case STOP, CONTINUE:
+		break
+	default:
+		return fmt.Errorf("invalid on error policy. valid values \"%s\" or \"%s\"", STOP, CONTINUE)
+	}
+
+	return nil
+}
+
+func (f *Flags) Validate() error {
 	cmd := Command(f.Command)
+
+	switch cmd {
+	case DUMP, DIFF, PROTECT, UNPROTECT, BACKUPS, PURGE:
+		// команды работают только с базой данных и не читают миграции
+	default:
+		if f.MigrationsDir == "" {
+			return errors.New("migrations dir is required")
+		}
+	}
+
 	priority := Priority(f.Priority)
 
 	switch priority {
@@ -43,13 +223,99 @@ func (f *Flags) Validate() error {
 		return fmt.Errorf("invalid priority. valid values \"%s\" or \"%s\"", DB, FS)
 	}
 
+	switch OutputFormat(f.Output) {
+	case "", OUTPUT_TEXT, OUTPUT_JSON, OUTPUT_TABLE:
+		break
+	default:
+		return fmt.Errorf("invalid output. valid values \"%s\", \"%s\" or \"%s\"", OUTPUT_TEXT, OUTPUT_JSON, OUTPUT_TABLE)
+	}
+
+	switch f.LogLevel {
+	case "", "debug", "info", "warn", "error":
+		break
+	default:
+		return errors.New("invalid log level. valid values \"debug\", \"info\", \"warn\" or \"error\"")
+	}
+
+	if f.StatementTimeout < 0 {
+		return errors.New("statement timeout can't be negative")
+	}
+
+	if f.Quiet && f.Verbose {
+		return errors.New("quiet and verbose can't be used together")
+	}
+
+	if f.FromUp != "" && cmd != CREATE {
+		return fmt.Errorf("from up is supported only by \"%s\" command", CREATE)
+	}
+
+	if f.Check && cmd != DUMP {
+		return fmt.Errorf("check is supported only by \"%s\" command", DUMP)
+	}
+
+	if (f.Before != "" || f.ArchiveDir != "") && cmd != SQUASH {
+		return fmt.Errorf("before and archive dir are supported only by \"%s\" command", SQUASH)
+	}
+
+	if f.MaxReverts < 0 {
+		return errors.New("max reverts can't be negative")
+	}
+
+	if f.MaxLostRows < 0 {
+		return errors.New("max lost rows can't be negative")
+	}
+
+	if f.OlderThan != 0 && cmd != PURGE {
+		return fmt.Errorf("older than is supported only by \"%s\" command", PURGE)
+	}
+
+	if f.OlderThan < 0 {
+		return errors.New("older than can't be negative")
+	}
+
+	if (len(f.LintRules) > 0 || f.Pending) && cmd != LINT {
+		return fmt.Errorf("lint rules and pending are supported only by \"%s\" command", LINT)
+	}
+
 	switch cmd {
 	case CREATE:
 		if match, err := regexp.MatchString(`^[a-zA-Z0-9_]+$`, f.MigrationName); err == nil && !match {
 			return fmt.Errorf("migration name might contain only letters, numbers and \"_\" symbol")
 		}
-	case DOWN, MIGRATE:
-		if match, err := regexp.MatchString(`^[a-zA-Z0-9_]+$`, f.MigrationsTableSchema); err == nil && !match {
+
+		switch MigrationFormat(f.Format) {
+		case "", PAIR, SINGLE:
+			break
+		default:
+			return fmt.Errorf("invalid migration format. valid values \"%s\" or \"%s\"", PAIR, SINGLE)
+		}
+	case DOWN, MIGRATE, VERIFY, SQUASH, PROTECT, UNPROTECT, BACKUPS, PURGE:
+		tenantMode := f.Tenants != "" || f.TenantsQuery != "" || f.TenantsPattern != ""
+		fanOutMode := len(f.ConnectionStrings) > 0 || f.ConnectionStringsFile != ""
+
+		if fanOutMode {
+			if cmd != MIGRATE {
+				return fmt.Errorf("multiple connection strings are supported only by \"%s\" command", MIGRATE)
+			}
+
+			if tenantMode {
+				return errors.New("tenants can't be combined with multiple connection strings")
+			}
+
+			if err := f.validateParallel(); err != nil {
+				return err
+			}
+		}
+
+		if tenantMode {
+			if cmd != MIGRATE {
+				return fmt.Errorf("tenants are supported only by \"%s\" command", MIGRATE)
+			}
+
+			if err := f.validateTenants(); err != nil {
+				return err
+			}
+		} else if match, err := regexp.MatchString(`^[a-zA-Z0-9_]+$`, f.MigrationsTableSchema); err == nil && !match {
 			return fmt.Errorf("migrations table schema might contain only letters, numbers and \"_\" symbol")
 		}
 
@@ -57,11 +323,57 @@ func (f *Flags) Validate() error {
 			return fmt.Errorf("migrations table might contain only letters, numbers and \"_\" symbol")
 		}
 
+		if f.ConnectionString == "" && !fanOutMode {
+			return errors.New("connection string is required")
+		}
+
+		if cmd == SQUASH {
+			if f.Before == "" {
+				return errors.New("before migration name is required")
+			}
+
+			switch MigrationFormat(f.Format) {
+			case "", PAIR, SINGLE:
+				break
+			default:
+				return fmt.Errorf("invalid migration format. valid values \"%s\" or \"%s\"", PAIR, SINGLE)
+			}
+		}
+	case LINT:
+		for _, r := range f.LintRules {
+			if name, severity, found := strings.Cut(r, "="); !found || strings.TrimSpace(name) == "" || strings.TrimSpace(severity) == "" {
+				return fmt.Errorf("invalid lint rule \"%s\". expected rule=severity format", r)
+			}
+		}
+
+		if f.Pending && f.ConnectionString == "" {
+			return errors.New("connection string is required to lint pending migrations")
+		}
+	case DUMP:
+		if err := f.validateSchemas(); err != nil {
+			return err
+		}
+
+		if f.SchemaFile == "" {
+			return errors.New("schema file is required")
+		}
+
 		if f.ConnectionString == "" {
 			return errors.New("connection string is required")
 		}
+	case DIFF:
+		if err := f.validateSchemas(); err != nil {
+			return err
+		}
+
+		if f.Source == "" || f.Target == "" {
+			return errors.New("source and target connection strings are required")
+		}
 	default:
-		return fmt.Errorf("invalid command. valid cli \"%s\", \"%s\", \"%s\"", CREATE, MIGRATE, DOWN)
+		return fmt.Errorf(
+			"invalid command. valid cli \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\"",
+			CREATE, MIGRATE, DOWN, DUMP, DIFF, VERIFY, SQUASH, LINT, PROTECT, UNPROTECT, BACKUPS, PURGE,
+		)
 	}
 
 	return nil
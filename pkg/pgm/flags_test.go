@@ -2,6 +2,7 @@ package pgm
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,7 +35,7 @@ func Test_Validate(t *testing.T) {
 		flags.MigrationsDir = "/migrations"
 		err := flags.Validate()
 
-		assert.EqualError(t, err, "invalid command. valid cli \"create\", \"migrate\", \"down\"")
+		assert.EqualError(t, err, "invalid command. valid cli \"create\", \"migrate\", \"down\", \"dump\", \"diff\", \"verify\", \"squash\", \"lint\", \"protect\", \"unprotect\", \"backups\", \"purge\"")
 	})
 
 	t.Run("should return error if create command & migration name contains forbidden symbols", func(t *testing.T) {
@@ -48,6 +49,18 @@ func Test_Validate(t *testing.T) {
 		assert.EqualError(t, err, "migration name might contain only letters, numbers and \"_\" symbol")
 	})
 
+	t.Run("should return error if create command & invalid migration format", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = "create"
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationName = "initial"
+		flags.Format = "triple"
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "invalid migration format. valid values \"pair\" or \"single\"")
+	})
+
 	t.Run("should return error if migrate command & migrations schema contains forbidden symbols", func(t *testing.T) {
 		flags := new(Flags)
 		flags.Priority = string(DB)
@@ -106,4 +119,324 @@ func Test_Validate(t *testing.T) {
 
 		assert.Nil(t, err)
 	})
+
+	t.Run("should return error if several tenant sources are specified", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "some connection string"
+		flags.Tenants = "tenant_1,tenant_2"
+		flags.TenantsPattern = "tenant_%"
+		flags.Parallel = 1
+		flags.OnError = string(STOP)
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "only one of tenants, tenants query or tenants pattern might be specified")
+	})
+
+	t.Run("should return error if tenants are used with down command", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(DOWN)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "some connection string"
+		flags.TenantsPattern = "tenant_%"
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "tenants are supported only by \"migrate\" command")
+	})
+
+	t.Run("should pass validation for migrate command in tenant mode without migrations schema", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "some connection string"
+		flags.Tenants = "tenant_1, tenant_2"
+		flags.Parallel = 4
+		flags.OnError = string(CONTINUE)
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.True(t, opts.IsTenantMode())
+		assert.Equal(t, []string{"tenant_1", "tenant_2"}, opts.Tenants)
+	})
+
+	t.Run("should pass validation for migrate command with multiple connection strings", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTableSchema = "public"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionStrings = []string{"postgres://eu/app", "postgres://us/app"}
+		flags.Parallel = 2
+		flags.OnError = string(CONTINUE)
+		flags.FailFast = true
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.True(t, opts.IsFanOutMode())
+		assert.Equal(t, FAIL_FAST, opts.OnError)
+	})
+
+	t.Run("should return error if multiple connection strings are combined with tenants", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionStringsFile = "/databases.txt"
+		flags.TenantsPattern = "tenant_%"
+		flags.Parallel = 1
+		flags.OnError = string(STOP)
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "tenants can't be combined with multiple connection strings")
+	})
+
+	t.Run("should return error if invalid output", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(CREATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationName = "initial"
+		flags.Output = "yaml"
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "invalid output. valid values \"text\", \"json\" or \"table\"")
+	})
+
+	t.Run("should return error if from up is used without create", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.ConnectionString = "postgres://localhost/db"
+		flags.FromUp = "up.sql"
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "from up is supported only by \"create\" command")
+	})
+
+	t.Run("should pass validation for dump command without migrations dir", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(DUMP)
+		flags.ConnectionString = "postgres://localhost/db"
+		flags.SchemaFile = "schema.sql"
+		flags.Schemas = "public, billing"
+		flags.Check = true
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.Equal(t, []string{"public", "billing"}, opts.Schemas)
+		assert.True(t, opts.Check)
+	})
+
+	t.Run("should return error if tenants are used with verify command", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(VERIFY)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "some connection string"
+		flags.Tenants = "tenant_1"
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "tenants are supported only by \"migrate\" command")
+	})
+
+	t.Run("should validate revert guard", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(FS)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTableSchema = "public"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "postgres://localhost/db"
+		flags.MaxReverts = -1
+		assert.EqualError(t, flags.Validate(), "max reverts can't be negative")
+
+		flags.MaxReverts = 3
+		flags.Protected = true
+		flags.AllowRevert = true
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.Equal(t, 3, opts.MaxReverts)
+		assert.True(t, opts.Protected)
+		assert.True(t, opts.AllowRevert)
+
+		flags.MaxLostRows = -1
+		assert.EqualError(t, flags.Validate(), "max lost rows can't be negative")
+
+		flags.MaxLostRows = 1000
+		flags.ForceDataLoss = true
+		assert.Nil(t, flags.Validate())
+
+		opts = flags.ToMigratorOptions()
+		assert.Equal(t, int64(1000), opts.MaxLostRows)
+		assert.True(t, opts.ForceDataLoss)
+
+		flags.Command = string(PROTECT)
+		assert.Nil(t, flags.Validate())
+
+		flags.MigrationsDir = ""
+		assert.Nil(t, flags.Validate())
+
+		flags.Command = string(UNPROTECT)
+		assert.Nil(t, flags.Validate())
+	})
+
+	t.Run("should validate backups", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(FS)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTableSchema = "public"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "postgres://localhost/db"
+		flags.BackupOnRevert = true
+		flags.OlderThan = time.Hour
+		assert.EqualError(t, flags.Validate(), "older than is supported only by \"purge\" command")
+
+		flags.Command = string(PURGE)
+		flags.OlderThan = -time.Hour
+		assert.EqualError(t, flags.Validate(), "older than can't be negative")
+
+		flags.OlderThan = 24 * time.Hour
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.True(t, opts.BackupOnRevert)
+		assert.Equal(t, 24*time.Hour, opts.OlderThan)
+
+		flags.Command = string(BACKUPS)
+		flags.OlderThan = 0
+		assert.Nil(t, flags.Validate())
+
+		flags.MigrationsDir = ""
+		assert.Nil(t, flags.Validate())
+
+		flags.Command = string(PURGE)
+		assert.Nil(t, flags.Validate())
+	})
+
+	t.Run("should validate lint rules", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(FS)
+		flags.Command = string(LINT)
+		flags.MigrationsDir = "/migrations"
+		flags.LintRules = []string{"drop-column"}
+		assert.EqualError(t, flags.Validate(), "invalid lint rule \"drop-column\". expected rule=severity format")
+
+		flags.LintRules = []string{"drop-column=error", " rename = off"}
+		flags.Pending = true
+		assert.EqualError(t, flags.Validate(), "connection string is required to lint pending migrations")
+
+		flags.ConnectionString = "postgres://localhost/db"
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.Equal(t, map[string]string{"drop-column": "error", "rename": "off"}, opts.LintRules)
+		assert.True(t, opts.Pending)
+	})
+
+	t.Run("should return error if squash before is not set", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(SQUASH)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationsTableSchema = "public"
+		flags.MigrationsTable = "migrations"
+		flags.ConnectionString = "postgres://localhost/scratch"
+		assert.EqualError(t, flags.Validate(), "before migration name is required")
+
+		flags.Before = "1700000000000_add_orders"
+		flags.ArchiveDir = "/migrations_archive"
+		assert.Nil(t, flags.Validate())
+
+		opts := flags.ToMigratorOptions()
+		assert.Equal(t, "1700000000000_add_orders", opts.Before)
+		assert.Equal(t, "/migrations_archive", opts.ArchiveDir)
+
+		flags.Command = string(MIGRATE)
+		assert.EqualError(t, flags.Validate(), "before and archive dir are supported only by \"squash\" command")
+	})
+
+	t.Run("should return error if diff target is not set", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(DIFF)
+		flags.Source = "postgres://staging/app"
+		flags.Schemas = "public,billing-v2"
+		assert.EqualError(t, flags.Validate(), "schema \"billing-v2\" might contain only letters, numbers and \"_\" symbol")
+
+		flags.Schemas = "public"
+		assert.EqualError(t, flags.Validate(), "source and target connection strings are required")
+
+		flags.Target = "postgres://prod/app"
+		assert.Nil(t, flags.Validate())
+	})
+
+	t.Run("should return error if check is used without dump", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.ConnectionString = "postgres://localhost/db"
+		flags.Check = true
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "check is supported only by \"dump\" command")
+	})
+
+	t.Run("should return error if statement timeout is negative", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(MIGRATE)
+		flags.MigrationsDir = "/migrations"
+		flags.ConnectionString = "postgres://localhost/db"
+		flags.StatementTimeout = -time.Second
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "statement timeout can't be negative")
+	})
+
+	t.Run("should return error if quiet and verbose are used together", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(CREATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationName = "initial"
+		flags.Quiet = true
+		flags.Verbose = true
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "quiet and verbose can't be used together")
+	})
+
+	t.Run("should return error if invalid log level", func(t *testing.T) {
+		flags := new(Flags)
+		flags.Priority = string(DB)
+		flags.Command = string(CREATE)
+		flags.MigrationsDir = "/migrations"
+		flags.MigrationName = "initial"
+		flags.LogLevel = "trace"
+		err := flags.Validate()
+
+		assert.EqualError(t, err, "invalid log level. valid values \"debug\", \"info\", \"warn\" or \"error\"")
+	})
+}
+
+func Test_EffectiveLogLevel(t *testing.T) {
+	assert.Equal(t, "info", (&Flags{}).EffectiveLogLevel())
+	assert.Equal(t, "warn", (&Flags{LogLevel: "warn"}).EffectiveLogLevel())
+	assert.Equal(t, "error", (&Flags{LogLevel: "debug", Quiet: true}).EffectiveLogLevel())
+	assert.Equal(t, "debug", (&Flags{LogLevel: "error", Verbose: true}).EffectiveLogLevel())
 }
@@ -4,11 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/quadgod/pgm/pkg/pgm"
 )
 
+const singleFileTemplate = `-- pgm:up
+
+
+-- pgm:down
+
+`
+
 func touch(fileName string) error {
 	_, err := os.Stat(fileName)
 	if os.IsNotExist(err) {
@@ -57,6 +65,48 @@ func CreateMigration(migDir string, migName string) (*pgm.Migration, error) {
 	}
 
 	migration.Down = downMigrationFile
+	migration.Format = pgm.PAIR
+
+	return migration, nil
+}
+
+// CreateSingleFileMigration создает однофайловую миграцию с секциями up & down
+func CreateSingleFileMigration(migDir string, migName string) (*pgm.Migration, error) {
+	err := os.MkdirAll(migDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	migrationFile := path.Join(migDir, fmt.Sprintf("%d_%s.sql", now, migName))
+
+	if _, err = os.Stat(migrationFile); err == nil {
+		return nil, fmt.Errorf("migration file \"%s\" already exists", migrationFile)
+	}
+
+	if err = os.WriteFile(migrationFile, []byte(singleFileTemplate), 0644); err != nil {
+		return nil, err
+	}
+
+	migration := new(pgm.Migration)
+	migration.Name = fmt.Sprintf("%d_%s", now, migName)
+	migration.Up = migrationFile
+	migration.Down = migrationFile
+	migration.Format = pgm.SINGLE
 
 	return migration, nil
 }
+
+// WriteMigrationSql записывает up & down sql в файлы созданной миграции
+func WriteMigrationSql(migration *pgm.Migration, upSql string, downSql string) error {
+	if migration.Format == pgm.SINGLE {
+		content := fmt.Sprintf("-- pgm:up\n%s\n\n-- pgm:down\n%s\n", strings.TrimSpace(upSql), strings.TrimSpace(downSql))
+		return os.WriteFile(migration.Up, []byte(content), 0644)
+	}
+
+	if err := os.WriteFile(migration.Up, []byte(upSql), 0644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(migration.Down, []byte(downSql), 0644)
+}
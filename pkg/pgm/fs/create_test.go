@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"github.com/quadgod/pgm/pkg/pgm"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path"
@@ -44,4 +45,37 @@ func Test_CreateMigration(t *testing.T) {
 		}
 		assert.Len(t, migrationDirEntries2, 4)
 	})
+
+	t.Run("should create single file migration", func(t *testing.T) {
+		dir := t.TempDir()
+
+		migration, err := CreateSingleFileMigration(dir, "single_migration")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		assert.Equal(t, pgm.SINGLE, migration.Format)
+		assert.Equal(t, migration.Up, migration.Down)
+		assert.Equal(t, path.Join(dir, migration.Name+".sql"), migration.Up)
+
+		up, down, err := ReadMigrationSql(*migration)
+		assert.Nil(t, err)
+		assert.Equal(t, "", up)
+		assert.Equal(t, "", down)
+	})
+	t.Run("should write sql of single file migration", func(t *testing.T) {
+		migration, err := CreateSingleFileMigration(t.TempDir(), "users")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		if !assert.Nil(t, WriteMigrationSql(migration, "CREATE TABLE users (id INT);\n", "DROP TABLE users;\n")) {
+			t.FailNow()
+		}
+
+		up, down, err := ReadMigrationSql(*migration)
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE TABLE users (id INT);", up)
+		assert.Equal(t, "DROP TABLE users;", down)
+	})
 }
@@ -1,7 +1,9 @@
 package fs
 
 import (
+	"errors"
 	"fmt"
+	iofs "io/fs"
 	"os"
 	"path"
 	"regexp"
@@ -11,6 +13,37 @@ import (
 	"github.com/quadgod/pgm/pkg/pgm"
 )
 
+var callbackEvents = func() map[string]pgm.HookEvent {
+	events := make(map[string]pgm.HookEvent, len(pgm.HookEvents))
+	for _, event := range pgm.HookEvents {
+		events[string(event)+".sql"] = event
+	}
+	return events
+}()
+
+var (
+	filenameRegexp           = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+	singleFilenameRegexp     = regexp.MustCompile(`^[0-9]+_[a-zA-Z0-9_]+\.sql$`)
+	repeatableFilenameRegexp = regexp.MustCompile(`^R_[a-zA-Z0-9_]+\.sql$`)
+)
+
+// ReadOptions параметры чтения директории миграций
+type ReadOptions struct {
+	// Recursive включает обход поддиректорий
+	Recursive bool
+}
+
+// Source содержимое директории миграций
+type Source struct {
+	Migrations []pgm.Migration
+	// Repeatables повторяемые миграции, отсортированные по имени
+	Repeatables []pgm.Migration
+	// Callbacks sql файлы колбэков из корня директории миграций
+	Callbacks map[pgm.HookEvent]pgm.Callback
+	// Ignored .sql файлы, которые не были распознаны как миграции
+	Ignored []string
+}
+
 // group группирует отсортированные sql файлы в up & down пары
 func group(sortedFiles []string, migDir string) ([]pgm.Migration, error) {
 	migrations := make([]pgm.Migration, 0)
@@ -43,57 +76,236 @@ func group(sortedFiles []string, migDir string) ([]pgm.Migration, error) {
 		}
 
 		migration := new(pgm.Migration)
-		migration.Name = downName
+		migration.Name = path.Base(downName)
 		migration.Down = path.Join(migDir, sortedFiles[i])
 		migration.Up = path.Join(migDir, sortedFiles[i+1])
+		migration.Format = pgm.PAIR
 		migrations = append(migrations, *migration)
 	}
 
 	return migrations, nil
 }
 
-// ReadMigrationsDir читает список миграций из директории
-func ReadMigrationsDir(migDir string) ([]pgm.Migration, error) {
-	if _, err := os.Stat(migDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("migrations directory does not exist: %w", err)
+// sortByBaseName сортирует относительные пути файлов по имени файла независимо от директории
+func sortByBaseName(files []string) error {
+	sort.SliceStable(files, func(i, j int) bool {
+		bi, bj := path.Base(files[i]), path.Base(files[j])
+		if bi != bj {
+			return bi < bj
+		}
+		return files[i] < files[j]
+	})
+
+	for i := 1; i < len(files); i++ {
+		if path.Base(files[i-1]) == path.Base(files[i]) {
+			return fmt.Errorf(
+				"duplicate migration file \"%s\" found in \"%s\" and \"%s\"",
+				path.Base(files[i]),
+				path.Dir(files[i-1]),
+				path.Dir(files[i]),
+			)
+		}
 	}
 
-	dirEntries, err := os.ReadDir(migDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read migrations directory %q: %w", migDir, err)
+	return nil
+}
+
+// merge объединяет парные и однофайловые миграции в один отсортированный по имени список
+func merge(pairs []pgm.Migration, singles []pgm.Migration) ([]pgm.Migration, error) {
+	pairNames := make(map[string]struct{}, len(pairs))
+	for _, m := range pairs {
+		pairNames[m.Name] = struct{}{}
 	}
 
+	for _, m := range singles {
+		if _, found := pairNames[m.Name]; found {
+			return nil, fmt.Errorf("migration \"%s\" exists both as single file and as up/down pair", m.Name)
+		}
+	}
+
+	migrations := append(pairs, singles...)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+
+	return migrations, nil
+}
+
+// readSource читает содержимое файловой системы миграций.
+// Пути к файлам миграций формируются относительно prefix.
+func readSource(fsys iofs.FS, prefix string, opts ReadOptions) (*Source, error) {
+	source := &Source{
+		Callbacks: make(map[pgm.HookEvent]pgm.Callback),
+		Ignored:   make([]string, 0),
+	}
 	fNames := make([]string, 0)
-	for _, f := range dirEntries {
-		if f.IsDir() {
-			continue
+	singleNames := make([]string, 0)
+	repeatableNames := make([]string, 0)
+	var nameErr error
+
+	err := iofs.WalkDir(fsys, ".", func(rel string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
 
-		if !strings.HasSuffix(f.Name(), ".up.sql") && !strings.HasSuffix(f.Name(), ".down.sql") {
-			continue
+		if d.IsDir() {
+			// без Recursive поддиректории не читаются: в них могут лежать файлы, подключаемые через \ir
+			if rel != "." && !opts.Recursive {
+				return iofs.SkipDir
+			}
+			return nil
 		}
 
-		filenameRegexp, err := regexp.Compile(`^[a-zA-Z0-9_.]+$`)
-		if err != nil {
-			return nil, err
+		nested := strings.Contains(rel, "/")
+
+		if !strings.HasSuffix(d.Name(), ".sql") {
+			return nil
 		}
 
-		match := filenameRegexp.MatchString(f.Name())
-		if !match {
-			return nil, fmt.Errorf("invalid migration file name found \"%s\"", f.Name())
+		if event, found := callbackEvents[d.Name()]; found && !nested {
+			source.Callbacks[event] = pgm.Callback{Event: event, Path: path.Join(prefix, rel)}
+			return nil
 		}
 
-		fNames = append(fNames, f.Name())
+		if !strings.HasSuffix(d.Name(), ".up.sql") && !strings.HasSuffix(d.Name(), ".down.sql") {
+			if singleFilenameRegexp.MatchString(d.Name()) {
+				singleNames = append(singleNames, rel)
+			} else if repeatableFilenameRegexp.MatchString(d.Name()) {
+				repeatableNames = append(repeatableNames, rel)
+			} else {
+				source.Ignored = append(source.Ignored, rel)
+			}
+			return nil
+		}
+
+		if !filenameRegexp.MatchString(d.Name()) {
+			nameErr = fmt.Errorf("invalid migration file name found \"%s\"", d.Name())
+			return iofs.SkipAll
+		}
+
+		fNames = append(fNames, rel)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if nameErr != nil {
+		return nil, nameErr
 	}
 
-	if len(fNames) != 0 {
-		sort.Strings(fNames)
+	if err = sortByBaseName(fNames); err != nil {
+		return nil, err
 	}
 
-	migrations, err := group(fNames, migDir)
+	if err = sortByBaseName(singleNames); err != nil {
+		return nil, err
+	}
+
+	if err = sortByBaseName(repeatableNames); err != nil {
+		return nil, err
+	}
+
+	pairs, err := group(fNames, prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	return migrations, nil
+	singles := make([]pgm.Migration, 0, len(singleNames))
+	for _, f := range singleNames {
+		filePath := path.Join(prefix, f)
+		singles = append(singles, pgm.Migration{
+			Name:   strings.TrimSuffix(path.Base(f), ".sql"),
+			Up:     filePath,
+			Down:   filePath,
+			Format: pgm.SINGLE,
+		})
+	}
+
+	migrations, err := merge(pairs, singles)
+	if err != nil {
+		return nil, err
+	}
+
+	source.Migrations = migrations
+
+	source.Repeatables = make([]pgm.Migration, 0, len(repeatableNames))
+	for _, f := range repeatableNames {
+		source.Repeatables = append(source.Repeatables, pgm.Migration{
+			Name:   strings.TrimSuffix(path.Base(f), ".sql"),
+			Up:     path.Join(prefix, f),
+			Format: pgm.REPEATABLE,
+		})
+	}
+
+	return source, nil
+}
+
+// ReadSource читает содержимое директории миграций.
+// В рекурсивном режиме миграции ищутся во всех поддиректориях и упорядочиваются
+// по имени независимо от директории, в которой они лежат.
+func ReadSource(migDir string, opts ReadOptions) (*Source, error) {
+	if _, err := os.Stat(migDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("migrations directory does not exist: %w", err)
+	}
+
+	source, err := readSource(os.DirFS(migDir), migDir, opts)
+	if err != nil {
+		var pathErr *iofs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, fmt.Errorf("failed to read migrations directory %q: %w", migDir, err)
+		}
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// ReadSourceFS читает содержимое файловой системы миграций (embed.FS, os.DirFS, fstest.MapFS).
+// Миграции ищутся от корня fsys, пути к файлам миграций указываются относительно fsys.
+func ReadSourceFS(fsys iofs.FS, opts ReadOptions) (*Source, error) {
+	source, err := readSource(fsys, "", opts)
+	if err != nil {
+		var pathErr *iofs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, fmt.Errorf("failed to read migrations fs: %w", err)
+		}
+		return nil, err
+	}
+
+	for i := range source.Migrations {
+		source.Migrations[i].FS = fsys
+	}
+
+	for i := range source.Repeatables {
+		source.Repeatables[i].FS = fsys
+	}
+
+	for event, callback := range source.Callbacks {
+		callback.FS = fsys
+		source.Callbacks[event] = callback
+	}
+
+	return source, nil
+}
+
+// ReadMigrationsDir читает список миграций из директории
+func ReadMigrationsDir(migDir string) ([]pgm.Migration, error) {
+	source, err := ReadSource(migDir, ReadOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return source.Migrations, nil
+}
+
+// ReadMigrationsFS читает список миграций из корня файловой системы fsys
+func ReadMigrationsFS(fsys iofs.FS) ([]pgm.Migration, error) {
+	source, err := ReadSourceFS(fsys, ReadOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return source.Migrations, nil
 }
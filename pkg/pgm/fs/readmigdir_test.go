@@ -1,9 +1,11 @@
 package fs
 
 import (
+	iofs "io/fs"
 	"os"
 	"path"
 	"testing"
+	"testing/fstest"
 
 	"github.com/quadgod/pgm/pkg/pgm"
 	"github.com/stretchr/testify/assert"
@@ -97,6 +99,220 @@ func Test_ReadMigrationsDir(t *testing.T) {
 	})
 }
 
+func Test_ReadSource(t *testing.T) {
+	writeFiles := func(t *testing.T, dir string, files ...string) {
+		for _, f := range files {
+			filePath := path.Join(dir, f)
+			if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filePath, []byte("SELECT 1;"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("should skip subdirectories when not recursive", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir,
+			"1_a.up.sql", "1_a.down.sql",
+			"2025/2_b.up.sql", "2025/2_b.down.sql",
+			"shared/columns.sql",
+		)
+
+		source, err := ReadSource(dir, ReadOptions{})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Len(t, source.Migrations, 1)
+		assert.Equal(t, "1_a", source.Migrations[0].Name)
+		assert.Len(t, source.Ignored, 0)
+	})
+
+	t.Run("should read nested migrations ordered by name regardless of directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir,
+			"2026/3_c.up.sql", "2026/3_c.down.sql",
+			"2025/feature/1_a.up.sql", "2025/feature/1_a.down.sql",
+			"2_b.up.sql", "2_b.down.sql",
+			"2025/notes.sql",
+		)
+
+		source, err := ReadSource(dir, ReadOptions{Recursive: true})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Len(t, source.Migrations, 3) {
+			t.FailNow()
+		}
+		assert.Equal(t, "1_a", source.Migrations[0].Name)
+		assert.Equal(t, path.Join(dir, "2025/feature/1_a.up.sql"), source.Migrations[0].Up)
+		assert.Equal(t, path.Join(dir, "2025/feature/1_a.down.sql"), source.Migrations[0].Down)
+		assert.Equal(t, "2_b", source.Migrations[1].Name)
+		assert.Equal(t, "3_c", source.Migrations[2].Name)
+		assert.Equal(t, path.Join(dir, "2026/3_c.up.sql"), source.Migrations[2].Up)
+		assert.Equal(t, []string{"2025/notes.sql"}, source.Ignored)
+	})
+
+	t.Run("should not read because same migration found in different directories", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir,
+			"2025/1_a.up.sql", "2025/1_a.down.sql",
+			"2026/1_a.up.sql", "2026/1_a.down.sql",
+		)
+
+		source, err := ReadSource(dir, ReadOptions{Recursive: true})
+		assert.Nil(t, source)
+		assert.ErrorContains(t, err, "duplicate migration file \"1_a.down.sql\" found in \"2025\" and \"2026\"")
+	})
+
+	t.Run("should read single file migrations alongside up/down pairs", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir,
+			"1_a.up.sql", "1_a.down.sql",
+			"2_b.sql",
+			"3_c.up.sql", "3_c.down.sql",
+			"schema.sql",
+		)
+
+		source, err := ReadSource(dir, ReadOptions{})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Len(t, source.Migrations, 3) {
+			t.FailNow()
+		}
+		assert.Equal(t, "1_a", source.Migrations[0].Name)
+		assert.Equal(t, pgm.PAIR, source.Migrations[0].Format)
+		assert.Equal(t, "2_b", source.Migrations[1].Name)
+		assert.Equal(t, pgm.SINGLE, source.Migrations[1].Format)
+		assert.Equal(t, path.Join(dir, "2_b.sql"), source.Migrations[1].Up)
+		assert.Equal(t, path.Join(dir, "2_b.sql"), source.Migrations[1].Down)
+		assert.Equal(t, "3_c", source.Migrations[2].Name)
+		assert.Equal(t, []string{"schema.sql"}, source.Ignored)
+	})
+
+	t.Run("should read callback files from root of migrations dir", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir,
+			"1_a.up.sql", "1_a.down.sql",
+			"beforeMigrate.sql", "afterEachMigrate.sql",
+			"2025/afterMigrate.sql",
+		)
+
+		source, err := ReadSource(dir, ReadOptions{Recursive: true})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Len(t, source.Migrations, 1)
+		assert.Len(t, source.Callbacks, 2)
+		assert.Equal(t, path.Join(dir, "beforeMigrate.sql"), source.Callbacks[pgm.BEFORE_MIGRATE].Path)
+		assert.Equal(t, path.Join(dir, "afterEachMigrate.sql"), source.Callbacks[pgm.AFTER_EACH_MIGRATE].Path)
+		assert.Equal(t, []string{"2025/afterMigrate.sql"}, source.Ignored)
+
+		callbackSql, err := ReadCallbackSql(source.Callbacks[pgm.BEFORE_MIGRATE])
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT 1;", callbackSql)
+	})
+
+	t.Run("should read repeatable migrations ordered by name", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir,
+			"1_a.up.sql", "1_a.down.sql",
+			"R_views.sql",
+			"2025/R_functions.sql",
+		)
+
+		source, err := ReadSource(dir, ReadOptions{Recursive: true})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Len(t, source.Migrations, 1)
+		if !assert.Len(t, source.Repeatables, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, "R_functions", source.Repeatables[0].Name)
+		assert.Equal(t, path.Join(dir, "2025/R_functions.sql"), source.Repeatables[0].Up)
+		assert.Equal(t, pgm.REPEATABLE, source.Repeatables[0].Format)
+		assert.Equal(t, "R_views", source.Repeatables[1].Name)
+		assert.Len(t, source.Ignored, 0)
+
+		up, down, err := ReadMigrationSql(source.Repeatables[1])
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT 1;", up)
+		assert.Equal(t, "", down)
+	})
+
+	t.Run("should not read because migration exists in both formats", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, "1_a.up.sql", "1_a.down.sql", "1_a.sql")
+
+		source, err := ReadSource(dir, ReadOptions{})
+		assert.Nil(t, source)
+		assert.EqualError(t, err, "migration \"1_a\" exists both as single file and as up/down pair")
+	})
+
+	t.Run("should not read because up and down are in different directories", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, "2025/1_a.down.sql", "2026/1_a.up.sql")
+
+		source, err := ReadSource(dir, ReadOptions{Recursive: true})
+		assert.Nil(t, source)
+		assert.ErrorContains(t, err, "not found up migration for \"2025/1_a.down.sql\" migration")
+	})
+}
+
+func Test_ReadSourceFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_a.up.sql":        {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/1_a.down.sql":      {Data: []byte("DROP TABLE a;")},
+		"migrations/2025/2_b.sql":      {Data: []byte("-- pgm:up\nCREATE TABLE b (id INT);\n-- pgm:down\nDROP TABLE b;\n")},
+		"migrations/2025/readme.md":    {Data: []byte("# migrations")},
+		"migrations/2026/3_c.up.sql":   {Data: []byte("CREATE TABLE c (id INT);")},
+		"migrations/2026/3_c.down.sql": {Data: []byte("DROP TABLE c;")},
+	}
+
+	sub, err := iofs.Sub(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should read migrations from fs", func(t *testing.T) {
+		migrations, err := ReadMigrationsFS(sub)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Len(t, migrations, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, "1_a", migrations[0].Name)
+		assert.Equal(t, "1_a.up.sql", migrations[0].Up)
+		assert.Equal(t, "1_a.down.sql", migrations[0].Down)
+
+		up, down, err := ReadMigrationSql(migrations[0])
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE TABLE a (id INT);", up)
+		assert.Equal(t, "DROP TABLE a;", down)
+	})
+
+	t.Run("should read nested migrations from fs", func(t *testing.T) {
+		source, err := ReadSourceFS(sub, ReadOptions{Recursive: true})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Len(t, source.Migrations, 3) {
+			t.FailNow()
+		}
+		assert.Equal(t, "2_b", source.Migrations[1].Name)
+		assert.Equal(t, "2025/2_b.sql", source.Migrations[1].Up)
+
+		up, down, err := ReadMigrationSql(source.Migrations[1])
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE TABLE b (id INT);", up)
+		assert.Equal(t, "DROP TABLE b;", down)
+	})
+}
+
 func Test_group(t *testing.T) {
 	migrationsDir := "/migrations"
 
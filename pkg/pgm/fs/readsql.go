@@ -0,0 +1,124 @@
+package fs
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+var sectionMarkerRegexp = regexp.MustCompile(`(?m)^[ \t]*--[ \t]*pgm:(up|down)[ \t]*\r?$`)
+
+// splitSections разбирает однофайловую миграцию на up & down секции
+func splitSections(content string) (string, string, error) {
+	markers := sectionMarkerRegexp.FindAllStringSubmatchIndex(content, -1)
+
+	if len(markers) != 2 {
+		return "", "", fmt.Errorf("expected exactly one \"-- pgm:up\" and one \"-- pgm:down\" marker, found %d markers", len(markers))
+	}
+
+	upMarker, downMarker := markers[0], markers[1]
+	if content[upMarker[2]:upMarker[3]] != "up" || content[downMarker[2]:downMarker[3]] != "down" {
+		return "", "", fmt.Errorf("\"-- pgm:up\" section must precede \"-- pgm:down\" section")
+	}
+
+	for _, line := range strings.Split(content[:upMarker[0]], "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return "", "", fmt.Errorf("unexpected sql before \"-- pgm:up\" marker")
+		}
+	}
+
+	up := strings.TrimSpace(content[upMarker[1]:downMarker[0]])
+	down := strings.TrimSpace(content[downMarker[1]:])
+
+	return up, down, nil
+}
+
+// readFile читает файл миграции из файловой системы миграции или с диска
+func readFile(migration pgm.Migration, filePath string) ([]byte, error) {
+	if migration.FS != nil {
+		return iofs.ReadFile(migration.FS, filePath)
+	}
+
+	return os.ReadFile(filePath)
+}
+
+// ReadMigrationFile читает файл миграции целиком, вместе с маркерами секций однофайловой миграции
+func ReadMigrationFile(migration pgm.Migration, filePath string) (string, error) {
+	content, err := readFile(migration, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// ReadMigrationSql читает up & down sql миграции
+func ReadMigrationSql(migration pgm.Migration) (string, string, error) {
+	if migration.Format == pgm.SINGLE {
+		content, err := readFile(migration, migration.Up)
+		if err != nil {
+			return "", "", err
+		}
+
+		up, down, err := splitSections(string(content))
+		if err != nil {
+			return "", "", fmt.Errorf("invalid migration file \"%s\": %w", migration.Up, err)
+		}
+
+		return up, down, nil
+	}
+
+	upSqlBytes, err := readFile(migration, migration.Up)
+	if err != nil {
+		return "", "", err
+	}
+
+	if migration.Format == pgm.REPEATABLE {
+		return string(upSqlBytes), "", nil
+	}
+
+	downSqlBytes, err := readFile(migration, migration.Down)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(upSqlBytes), string(downSqlBytes), nil
+}
+
+// ReadCallbackSql читает sql файла колбэка
+func ReadCallbackSql(callback pgm.Callback) (string, error) {
+	var content []byte
+	var err error
+
+	if callback.FS != nil {
+		content, err = iofs.ReadFile(callback.FS, callback.Path)
+	} else {
+		content, err = os.ReadFile(callback.Path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// SqlLineOffset возвращает количество строк файла миграции, предшествующих sql.
+// Используется, чтобы перевести строку ошибки в sql секции в строку файла.
+func SqlLineOffset(migration pgm.Migration, filePath string, sql string) (int, error) {
+	content, err := readFile(migration, filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	idx := strings.Index(string(content), sql)
+	if sql == "" || idx < 0 {
+		return 0, fmt.Errorf("sql not found in file \"%s\"", filePath)
+	}
+
+	return strings.Count(string(content[:idx]), "\n"), nil
+}
@@ -0,0 +1,39 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitSections(t *testing.T) {
+	t.Run("should split single file migration into up and down sql", func(t *testing.T) {
+		content := "-- users table\n-- pgm:up\nCREATE TABLE users (id INT);\n\n-- pgm:down\nDROP TABLE users;\n"
+		up, down, err := splitSections(content)
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE TABLE users (id INT);", up)
+		assert.Equal(t, "DROP TABLE users;", down)
+	})
+
+	t.Run("should allow empty down section", func(t *testing.T) {
+		up, down, err := splitSections("-- pgm:up\nSELECT 1;\n--pgm:down\n")
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT 1;", up)
+		assert.Equal(t, "", down)
+	})
+
+	t.Run("should not split because down marker is missing", func(t *testing.T) {
+		_, _, err := splitSections("-- pgm:up\nSELECT 1;\n")
+		assert.EqualError(t, err, "expected exactly one \"-- pgm:up\" and one \"-- pgm:down\" marker, found 1 markers")
+	})
+
+	t.Run("should not split because sections are in wrong order", func(t *testing.T) {
+		_, _, err := splitSections("-- pgm:down\nDROP TABLE users;\n-- pgm:up\nCREATE TABLE users (id INT);\n")
+		assert.EqualError(t, err, "\"-- pgm:up\" section must precede \"-- pgm:down\" section")
+	})
+
+	t.Run("should not split because sql found before up marker", func(t *testing.T) {
+		_, _, err := splitSections("SELECT 1;\n-- pgm:up\nSELECT 2;\n-- pgm:down\n")
+		assert.EqualError(t, err, "unexpected sql before \"-- pgm:up\" marker")
+	})
+}
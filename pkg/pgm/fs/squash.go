@@ -0,0 +1,127 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+var squashedMarkerRegexp = regexp.MustCompile(`(?m)^[ \t]*--[ \t]*pgm:squashed[ \t]+(.+?)[ \t]*\r?$`)
+
+// ParseSquashed разбирает строки "-- pgm:squashed <name> [| <name> <name> ...]" из up sql базовой миграции.
+// После имени миграции через "|" перечисляются последовательности миграций, объединенных в нее ранее.
+func ParseSquashed(upSql string) []pgm.SquashedMigration {
+	squashed := make([]pgm.SquashedMigration, 0)
+
+	for _, match := range squashedMarkerRegexp.FindAllStringSubmatch(upSql, -1) {
+		parts := strings.Split(match[1], "|")
+		name := strings.TrimSpace(parts[0])
+		if name == "" {
+			continue
+		}
+
+		s := pgm.SquashedMigration{Name: name}
+		for _, part := range parts[1:] {
+			if names := strings.Fields(part); len(names) > 0 {
+				s.Expansions = append(s.Expansions, names)
+			}
+		}
+
+		squashed = append(squashed, s)
+	}
+
+	return squashed
+}
+
+// FormatSquashed формирует строки "-- pgm:squashed" для up sql базовой миграции
+func FormatSquashed(squashed []pgm.SquashedMigration) string {
+	var sb strings.Builder
+	for _, s := range squashed {
+		sb.WriteString("-- pgm:squashed " + s.Name)
+		for _, expansion := range s.Expansions {
+			sb.WriteString(" | " + strings.Join(expansion, " "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ReadSquashed читает список миграций, объединенных в базовую миграцию
+func ReadSquashed(migration pgm.Migration) ([]pgm.SquashedMigration, error) {
+	if migration.Format == pgm.GO || migration.Format == pgm.REPEATABLE {
+		return nil, nil
+	}
+
+	upSql, _, err := ReadMigrationSql(migration)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSquashed(upSql), nil
+}
+
+// CreateBaselineMigration создает базовую миграцию с именем name и заданным sql
+func CreateBaselineMigration(migDir string, name string, format pgm.MigrationFormat, upSql string, downSql string) (*pgm.Migration, error) {
+	if err := os.MkdirAll(migDir, 0755); err != nil {
+		return nil, err
+	}
+
+	migration := &pgm.Migration{Name: name, Format: format}
+	if format == pgm.SINGLE {
+		migration.Up = path.Join(migDir, name+".sql")
+		migration.Down = migration.Up
+	} else {
+		migration.Up = path.Join(migDir, name+".up.sql")
+		migration.Down = path.Join(migDir, name+".down.sql")
+	}
+
+	for _, file := range []string{migration.Up, migration.Down} {
+		if _, err := os.Stat(file); err == nil {
+			return nil, fmt.Errorf("migration file \"%s\" already exists", file)
+		}
+	}
+
+	if err := WriteMigrationSql(migration, upSql, downSql); err != nil {
+		return nil, err
+	}
+
+	return migration, nil
+}
+
+// migrationFiles возвращает файлы миграции
+func migrationFiles(migration pgm.Migration) []string {
+	if migration.Down == "" || migration.Down == migration.Up {
+		return []string{migration.Up}
+	}
+	return []string{migration.Up, migration.Down}
+}
+
+// RemoveMigration удаляет файлы миграции или, если задана archiveDir, переносит их в archiveDir.
+// Возвращает пути удаленных файлов.
+func RemoveMigration(migration pgm.Migration, archiveDir string) ([]string, error) {
+	files := migrationFiles(migration)
+
+	if archiveDir != "" {
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range files {
+		var err error
+		if archiveDir != "" {
+			err = os.Rename(file, path.Join(archiveDir, path.Base(file)))
+		} else {
+			err = os.Remove(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseSquashed(t *testing.T) {
+	squashed := []pgm.SquashedMigration{
+		{Name: "1_a"},
+		{Name: "2_baseline", Expansions: [][]string{{"1_x", "2_y"}, {"1_x", "2_z"}}},
+	}
+
+	header := FormatSquashed(squashed)
+	assert.Equal(t, "-- pgm:squashed 1_a\n-- pgm:squashed 2_baseline | 1_x 2_y | 1_x 2_z\n", header)
+
+	assert.Equal(t, squashed, ParseSquashed(header+"\nCREATE TABLE public.users (id bigint);\n"))
+	assert.Empty(t, ParseSquashed("CREATE TABLE public.users (id bigint);"))
+}
+
+func Test_CreateBaselineMigration(t *testing.T) {
+	dir := t.TempDir()
+	archiveDir := path.Join(dir, "archive")
+
+	migration, err := CreateBaselineMigration(dir, "2_baseline", pgm.SINGLE, "-- pgm:squashed 1_a\nCREATE TABLE t (id int);", "DROP TABLE t;")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	squashed, err := ReadSquashed(*migration)
+	assert.Nil(t, err)
+	assert.Equal(t, []pgm.SquashedMigration{{Name: "1_a"}}, squashed)
+
+	_, err = CreateBaselineMigration(dir, "2_baseline", pgm.SINGLE, "", "")
+	assert.NotNil(t, err)
+
+	files, err := RemoveMigration(*migration, archiveDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(dir, "2_baseline.sql")}, files)
+	assert.FileExists(t, path.Join(archiveDir, "2_baseline.sql"))
+
+	_, err = os.Stat(migration.Up)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,68 @@
+package pgm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GO миграция, реализованная go функциями и зарегистрированная через RegisterGoMigration
+const GO MigrationFormat = "go"
+
+// MigrationFunc функция go миграции, выполняется в транзакции мигратора
+type MigrationFunc func(ctx context.Context, tx pgx.Tx) error
+
+var (
+	goMigrationNameRegexp = regexp.MustCompile(`^[0-9]+_[a-zA-Z0-9_]+$`)
+	goMigrationsMu        sync.Mutex
+	goMigrations          = make(map[string]Migration)
+)
+
+// RegisterGoMigration регистрирует go миграцию. Предназначена для вызова из init().
+// Имя миграции должно иметь вид {timestamp}_{name}, чтобы миграция упорядочивалась
+// вместе с sql миграциями из директории. Функция down может быть nil, но тогда
+// откатить миграцию будет невозможно.
+func RegisterGoMigration(name string, up MigrationFunc, down MigrationFunc) {
+	if !goMigrationNameRegexp.MatchString(name) {
+		panic(fmt.Sprintf("pgm: invalid go migration name \"%s\", expected {timestamp}_{name}", name))
+	}
+
+	if up == nil {
+		panic(fmt.Sprintf("pgm: go migration \"%s\" has no up function", name))
+	}
+
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+
+	if _, found := goMigrations[name]; found {
+		panic(fmt.Sprintf("pgm: go migration \"%s\" is already registered", name))
+	}
+
+	goMigrations[name] = Migration{
+		Name:     name,
+		Format:   GO,
+		UpFunc:   up,
+		DownFunc: down,
+	}
+}
+
+// GoMigrations возвращает зарегистрированные go миграции, отсортированные по имени
+func GoMigrations() []Migration {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+
+	migrations := make([]Migration, 0, len(goMigrations))
+	for _, m := range goMigrations {
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+
+	return migrations
+}
@@ -0,0 +1,50 @@
+package pgm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RegisterGoMigration(t *testing.T) {
+	noop := func(ctx context.Context, tx pgx.Tx) error { return nil }
+
+	t.Cleanup(func() {
+		goMigrations = make(map[string]Migration)
+	})
+
+	t.Run("should register go migrations sorted by name", func(t *testing.T) {
+		RegisterGoMigration("2_second", noop, nil)
+		RegisterGoMigration("1_first", noop, noop)
+
+		migrations := GoMigrations()
+		if !assert.Len(t, migrations, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, "1_first", migrations[0].Name)
+		assert.Equal(t, GO, migrations[0].Format)
+		assert.NotNil(t, migrations[0].DownFunc)
+		assert.Equal(t, "2_second", migrations[1].Name)
+		assert.Nil(t, migrations[1].DownFunc)
+	})
+
+	t.Run("should panic on duplicate go migration", func(t *testing.T) {
+		assert.PanicsWithValue(t, "pgm: go migration \"1_first\" is already registered", func() {
+			RegisterGoMigration("1_first", noop, noop)
+		})
+	})
+
+	t.Run("should panic on invalid go migration name", func(t *testing.T) {
+		assert.PanicsWithValue(t, "pgm: invalid go migration name \"reencrypt\", expected {timestamp}_{name}", func() {
+			RegisterGoMigration("reencrypt", noop, noop)
+		})
+	})
+
+	t.Run("should panic when up function is missing", func(t *testing.T) {
+		assert.PanicsWithValue(t, "pgm: go migration \"3_third\" has no up function", func() {
+			RegisterGoMigration("3_third", nil, noop)
+		})
+	})
+}
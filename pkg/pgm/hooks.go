@@ -0,0 +1,85 @@
+package pgm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// HookEvent точка жизненного цикла мигратора, в которой выполняются колбэки
+type HookEvent string
+
+const (
+	// BEFORE_MIGRATE перед применением миграций командой migrate
+	BEFORE_MIGRATE HookEvent = "beforeMigrate"
+	// BEFORE_EACH_MIGRATE перед применением каждой миграции
+	BEFORE_EACH_MIGRATE HookEvent = "beforeEachMigrate"
+	// AFTER_EACH_MIGRATE после применения каждой миграции
+	AFTER_EACH_MIGRATE HookEvent = "afterEachMigrate"
+	// AFTER_MIGRATE после применения миграций командой migrate
+	AFTER_MIGRATE HookEvent = "afterMigrate"
+	// BEFORE_REVERT перед откатом миграции командой down
+	BEFORE_REVERT HookEvent = "beforeRevert"
+	// BEFORE_EACH_REVERT перед откатом каждой миграции
+	BEFORE_EACH_REVERT HookEvent = "beforeEachRevert"
+	// AFTER_EACH_REVERT после отката каждой миграции
+	AFTER_EACH_REVERT HookEvent = "afterEachRevert"
+	// AFTER_REVERT после отката миграции командой down
+	AFTER_REVERT HookEvent = "afterRevert"
+)
+
+// HookEvents все поддерживаемые события в порядке их возможного выполнения
+var HookEvents = []HookEvent{
+	BEFORE_MIGRATE,
+	BEFORE_EACH_MIGRATE,
+	AFTER_EACH_MIGRATE,
+	AFTER_MIGRATE,
+	BEFORE_REVERT,
+	BEFORE_EACH_REVERT,
+	AFTER_EACH_REVERT,
+	AFTER_REVERT,
+}
+
+// Callback sql файл колбэка из директории миграций, например beforeMigrate.sql
+type Callback struct {
+	Event HookEvent
+	Path  string
+	// FS файловая система, из которой читается Path. Если nil, файл читается с диска
+	FS fs.FS
+}
+
+// HookInfo данные, передаваемые go колбэку
+type HookInfo struct {
+	Event HookEvent
+	// MigrationName имя миграции для событий beforeEach* и afterEach*
+	MigrationName string
+	// Result результат миграции для событий afterEach*
+	Result *MigrationResult
+	// Results результаты всех шагов для событий afterMigrate и afterRevert
+	Results []MigrationResult
+}
+
+// HookFunc go колбэк, выполняется в транзакции мигратора.
+// Ошибка колбэка прерывает выполнение команды, как и ошибка миграции.
+type HookFunc func(ctx context.Context, tx pgx.Tx, info HookInfo) error
+
+// HookError ошибка выполнения колбэка
+type HookError struct {
+	Event         HookEvent
+	MigrationName string
+	Err           error
+}
+
+func (e *HookError) Error() string {
+	if e.MigrationName != "" {
+		return fmt.Sprintf("%s hook error for %s migration: %v", e.Event, e.MigrationName, e.Err)
+	}
+
+	return fmt.Sprintf("%s hook error: %v", e.Event, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,207 @@
+// Package lint проверяет sql миграций на операции, опасные для работающей базы данных:
+// блокировки таблиц, перезапись таблиц и изменения схемы, несовместимые с уже развернутым кодом
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/fs"
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+)
+
+// Severity важность нарушения правила
+type Severity string
+
+const (
+	// ERROR нарушение правила завершает проверку ошибкой
+	ERROR Severity = "error"
+	// WARNING нарушение правила выводится, но не влияет на результат проверки
+	WARNING Severity = "warning"
+	// OFF правило не проверяется
+	OFF Severity = "off"
+)
+
+// Finding нарушение правила в sql миграции
+type Finding struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Migration string   `json:"migration"`
+	File      string   `json:"file"`
+	// Line строка файла, с которой начинается запрос
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Rule правило проверки запросов миграции
+type Rule struct {
+	Name     string
+	Severity Severity
+	// Down проверять правилом также down sql
+	Down  bool
+	check func(st sqlsplit.Statement, sec *section) []string
+}
+
+// section проверяемая секция миграции
+type section struct {
+	// created таблицы, созданные предыдущими запросами секции
+	created map[string]bool
+}
+
+var ignoreRegexp = regexp.MustCompile(`(?m)^[ \t]*--[ \t]*pgm:lint-ignore\b(.*?)\r?$`)
+
+// Configure возвращает правила rules с важностью из severities (имя правила - важность).
+// Возвращает ошибку для неизвестного правила или важности.
+func Configure(rules []Rule, severities map[string]string) ([]Rule, error) {
+	configured := append(make([]Rule, 0, len(rules)), rules...)
+
+	names := make([]string, 0, len(severities))
+	for name := range severities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		severity := Severity(severities[name])
+		switch severity {
+		case ERROR, WARNING, OFF:
+			break
+		default:
+			return nil, fmt.Errorf("invalid severity %s of lint rule %s. valid values \"%s\", \"%s\" or \"%s\"", severity, name, ERROR, WARNING, OFF)
+		}
+
+		found := false
+		for i := range configured {
+			if configured[i].Name == name {
+				configured[i].Severity = severity
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown lint rule %s", name)
+		}
+	}
+
+	return configured, nil
+}
+
+// ignored возвращает правила, отключенные в sql комментариями "-- pgm:lint-ignore rule [rule ...]".
+// Комментарий без имен правил отключает все правила.
+func ignored(sql string) (map[string]bool, bool) {
+	rules := make(map[string]bool)
+	all := false
+
+	for _, match := range ignoreRegexp.FindAllStringSubmatch(sql, -1) {
+		names := strings.FieldsFunc(match[1], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(names) == 0 {
+			all = true
+		}
+		for _, name := range names {
+			rules[name] = true
+		}
+	}
+
+	return rules, all
+}
+
+// lintSection проверяет sql секции миграции, записанной в файл file
+func lintSection(rules []Rule, migration pgm.Migration, file string, sql string, down bool) ([]Finding, error) {
+	statements, err := sqlsplit.Split(sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration file \"%s\": %w", file, err)
+	}
+
+	offset, err := fs.SqlLineOffset(migration, file, sql)
+	if err != nil {
+		offset = 0
+	}
+
+	content, err := fs.ReadMigrationFile(migration, file)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoredRules, ignoreAll := ignored(content)
+	if ignoreAll {
+		return nil, nil
+	}
+
+	findings := make([]Finding, 0)
+	sec := &section{created: make(map[string]bool)}
+
+	for _, st := range statements {
+		if st.Meta {
+			continue
+		}
+
+		for _, rule := range rules {
+			if rule.Severity == OFF || (down && !rule.Down) || ignoredRules[rule.Name] {
+				continue
+			}
+
+			for _, message := range rule.check(st, sec) {
+				findings = append(findings, Finding{
+					Rule:      rule.Name,
+					Severity:  rule.Severity,
+					Migration: migration.Name,
+					File:      file,
+					Line:      offset + st.Line,
+					Message:   message,
+				})
+			}
+		}
+
+		trackCreated(st, sec)
+	}
+
+	return findings, nil
+}
+
+// Lint проверяет up & down sql миграции правилами rules. Go миграции не проверяются.
+// Правила отключаются для файла комментарием "-- pgm:lint-ignore rule" в любом месте файла.
+func Lint(migration pgm.Migration, rules []Rule) ([]Finding, error) {
+	if migration.Format == pgm.GO {
+		return nil, nil
+	}
+
+	upSql, downSql, err := fs.ReadMigrationSql(migration)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := lintSection(rules, migration, migration.Up, upSql, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if migration.Format == pgm.REPEATABLE {
+		return findings, nil
+	}
+
+	downFindings, err := lintSection(rules, migration, migration.Down, downSql, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(findings, downFindings...), nil
+}
+
+// Count возвращает количество ошибок и предупреждений
+func Count(findings []Finding) (int, int) {
+	errors, warnings := 0, 0
+	for _, f := range findings {
+		switch f.Severity {
+		case ERROR:
+			errors++
+		case WARNING:
+			warnings++
+		}
+	}
+	return errors, warnings
+}
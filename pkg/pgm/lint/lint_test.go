@@ -0,0 +1,125 @@
+package lint
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMigration(t *testing.T, name string, content string) pgm.Migration {
+	file := path.Join(t.TempDir(), name+".sql")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return pgm.Migration{Name: name, Up: file, Down: file, Format: pgm.SINGLE}
+}
+
+func rules(findings []Finding) []string {
+	names := make([]string, 0, len(findings))
+	for _, f := range findings {
+		names = append(names, f.Rule)
+	}
+	return names
+}
+
+func Test_Lint(t *testing.T) {
+	t.Run("should report dangerous statements with file lines", func(t *testing.T) {
+		migration := writeMigration(t, "1_orders", `-- pgm:up
+ALTER TABLE orders ADD COLUMN status text NOT NULL, ADD COLUMN note text NOT NULL DEFAULT '';
+CREATE INDEX orders_status ON public.orders (status);
+ALTER TABLE orders ALTER COLUMN total TYPE numeric(12, 2);
+ALTER TABLE orders DROP COLUMN legacy, DROP CONSTRAINT orders_legacy_check;
+ALTER TABLE orders RENAME COLUMN amount TO total_amount;
+ALTER TABLE users RENAME TO customers;
+CREATE INDEX CONCURRENTLY orders_created ON orders (created_at);
+
+-- pgm:down
+ALTER TABLE orders ADD COLUMN legacy text;
+ALTER TABLE orders DROP COLUMN status;
+`)
+
+		findings, err := Lint(migration, Rules())
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		assert.Equal(t, []string{
+			"add-column-not-null",
+			"create-index-lock",
+			"alter-column-type",
+			"drop-column",
+			"rename",
+			"rename",
+			"concurrently-in-transaction",
+		}, rules(findings))
+
+		assert.Equal(t, 2, findings[0].Line)
+		assert.Equal(t, ERROR, findings[0].Severity)
+		assert.Equal(t, "column status is added to orders as NOT NULL without a default, the statement fails if the table has rows", findings[0].Message)
+		assert.Equal(t, 3, findings[1].Line)
+		assert.Equal(t, WARNING, findings[1].Severity)
+		assert.Equal(t, migration.Up, findings[1].File)
+
+		errors, warnings := Count(findings)
+		assert.Equal(t, 2, errors)
+		assert.Equal(t, 5, warnings)
+	})
+
+	t.Run("should skip tables created in the same migration", func(t *testing.T) {
+		migration := writeMigration(t, "1_users", `-- pgm:up
+CREATE TABLE users (id bigint);
+ALTER TABLE users ADD COLUMN email text NOT NULL;
+CREATE INDEX users_email ON users (email);
+
+-- pgm:down
+DROP TABLE users;
+`)
+
+		findings, err := Lint(migration, Rules())
+		assert.Nil(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("should ignore suppressed rules", func(t *testing.T) {
+		migration := writeMigration(t, "1_orders", `-- pgm:lint-ignore drop-column, create-index-lock
+-- pgm:up
+CREATE INDEX orders_status ON orders (status);
+ALTER TABLE orders DROP COLUMN legacy;
+ALTER TABLE orders RENAME COLUMN amount TO total_amount;
+
+-- pgm:down
+`)
+
+		findings, err := Lint(migration, Rules())
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"rename"}, rules(findings))
+
+		all := writeMigration(t, "2_orders", "-- pgm:up\n-- pgm:lint-ignore\nALTER TABLE orders DROP COLUMN legacy;\n-- pgm:down\n")
+		findings, err = Lint(all, Rules())
+		assert.Nil(t, err)
+		assert.Empty(t, findings)
+	})
+}
+
+func Test_Configure(t *testing.T) {
+	configured, err := Configure(Rules(), map[string]string{"drop-column": "error", "rename": "off"})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	migration := writeMigration(t, "1_orders", "-- pgm:up\nALTER TABLE orders DROP COLUMN legacy;\nALTER TABLE orders RENAME TO orders_v2;\n-- pgm:down\n")
+	findings, err := Lint(migration, configured)
+	assert.Nil(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, ERROR, findings[0].Severity)
+	}
+
+	_, err = Configure(Rules(), map[string]string{"drop-table": "error"})
+	assert.EqualError(t, err, "unknown lint rule drop-table")
+
+	_, err = Configure(Rules(), map[string]string{"rename": "fatal"})
+	assert.EqualError(t, err, "invalid severity fatal of lint rule rename. valid values \"error\", \"warning\" or \"off\"")
+}
@@ -0,0 +1,153 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm/ddl"
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+)
+
+var (
+	createIndexRegexp = regexp.MustCompile(
+		`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:(` + ddl.Ident + `)\s+)?ON\s+(?:ONLY\s+)?(` + ddl.QName + `)`,
+	)
+	concurrentlyRegexp = regexp.MustCompile(
+		`(?is)^(?:CREATE\s+(?:UNIQUE\s+)?INDEX|DROP\s+INDEX|REINDEX\s+(?:\([^)]*\)\s+)?[A-Za-z]+|ALTER\s+TABLE\s+.*\bDETACH\s+PARTITION\s+` + ddl.QName + `)\s+CONCURRENTLY\b`,
+	)
+	addConstraintRegexp    = regexp.MustCompile(`(?is)^ADD\s+(?:CONSTRAINT|PRIMARY|UNIQUE|CHECK|FOREIGN|EXCLUDE)\b`)
+	notNullRegexp          = regexp.MustCompile(`(?is)\bNOT\s+NULL\b`)
+	defaultRegexp          = regexp.MustCompile(`(?is)\b(?:DEFAULT|GENERATED)\b`)
+	alterTypeRegexp        = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(` + ddl.Ident + `)\s+(?:SET\s+DATA\s+)?TYPE\b`)
+	renameTableRegexp      = regexp.MustCompile(`(?is)^RENAME\s+TO\s+(` + ddl.Ident + `)$`)
+	renameConstraintRegexp = regexp.MustCompile(`(?is)^RENAME\s+CONSTRAINT\b`)
+	renameColumnRegexp     = regexp.MustCompile(`(?is)^RENAME\s+(?:COLUMN\s+)?(` + ddl.Ident + `)\s+TO\s+(` + ddl.Ident + `)$`)
+)
+
+// Rules возвращает правила проверки с важностью по умолчанию
+func Rules() []Rule {
+	return []Rule{
+		{Name: "add-column-not-null", Severity: ERROR, Down: true, check: checkAddColumnNotNull},
+		{Name: "create-index-lock", Severity: WARNING, Down: true, check: checkCreateIndexLock},
+		{Name: "concurrently-in-transaction", Severity: ERROR, Down: true, check: checkConcurrently},
+		{Name: "alter-column-type", Severity: WARNING, Down: true, check: checkAlterColumnType},
+		{Name: "drop-column", Severity: WARNING, check: checkDropColumn},
+		{Name: "rename", Severity: WARNING, check: checkRename},
+	}
+}
+
+// tableKey приводит имя таблицы к виду для сравнения: без схемы, кавычек и регистра
+func tableKey(name string) string {
+	parts := sqlsplit.SplitTopLevel(name, '.')
+	return strings.ToLower(strings.ReplaceAll(parts[len(parts)-1], `"`, ""))
+}
+
+// trackCreated запоминает таблицы, созданные запросом: изменения новых таблиц безопасны
+func trackCreated(st sqlsplit.Statement, sec *section) {
+	if m := ddl.CreateTableRegexp.FindStringSubmatch(sqlsplit.StripLeadingComments(st.SQL)); m != nil {
+		sec.created[tableKey(m[2])] = true
+	}
+}
+
+// alterActions возвращает таблицу и действия ALTER TABLE над таблицей, созданной до миграции
+func alterActions(st sqlsplit.Statement, sec *section) (string, []string) {
+	m := ddl.AlterTableRegexp.FindStringSubmatch(sqlsplit.StripLeadingComments(st.SQL))
+	if m == nil || sec.created[tableKey(m[2])] {
+		return "", nil
+	}
+	return m[2], sqlsplit.SplitTopLevel(m[3], ',')
+}
+
+func checkAddColumnNotNull(st sqlsplit.Statement, sec *section) []string {
+	table, actions := alterActions(st, sec)
+	messages := make([]string, 0)
+
+	for _, action := range actions {
+		if addConstraintRegexp.MatchString(action) {
+			continue
+		}
+
+		m := ddl.AddColumnRegexp.FindStringSubmatch(action)
+		if m != nil && notNullRegexp.MatchString(m[3]) && !defaultRegexp.MatchString(m[3]) {
+			messages = append(messages, fmt.Sprintf(
+				"column %s is added to %s as NOT NULL without a default, the statement fails if the table has rows", m[2], table,
+			))
+		}
+	}
+
+	return messages
+}
+
+func checkCreateIndexLock(st sqlsplit.Statement, sec *section) []string {
+	m := createIndexRegexp.FindStringSubmatch(sqlsplit.StripLeadingComments(st.SQL))
+	if m == nil || m[1] != "" || sec.created[tableKey(m[3])] {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("index %s blocks writes to %s until it is built, build indexes on large tables outside the migration with CONCURRENTLY", m[2], m[3])}
+}
+
+func checkConcurrently(st sqlsplit.Statement, _ *section) []string {
+	if !concurrentlyRegexp.MatchString(sqlsplit.StripLeadingComments(st.SQL)) {
+		return nil
+	}
+
+	return []string{"CONCURRENTLY can't run inside the migration transaction"}
+}
+
+func checkAlterColumnType(st sqlsplit.Statement, sec *section) []string {
+	table, actions := alterActions(st, sec)
+	messages := make([]string, 0)
+
+	for _, action := range actions {
+		if m := alterTypeRegexp.FindStringSubmatch(action); m != nil {
+			messages = append(messages, fmt.Sprintf(
+				"type change of column %s may rewrite %s under an exclusive lock", m[1], table,
+			))
+		}
+	}
+
+	return messages
+}
+
+func checkDropColumn(st sqlsplit.Statement, sec *section) []string {
+	table, actions := alterActions(st, sec)
+	messages := make([]string, 0)
+
+	for _, action := range actions {
+		if ddl.DropConstraintRegexp.MatchString(action) {
+			continue
+		}
+
+		if m := ddl.DropColumnRegexp.FindStringSubmatch(action); m != nil {
+			messages = append(messages, fmt.Sprintf(
+				"column %s is dropped from %s, code still reading it fails, stop reading the column in an earlier release", m[1], table,
+			))
+		}
+	}
+
+	return messages
+}
+
+func checkRename(st sqlsplit.Statement, sec *section) []string {
+	table, actions := alterActions(st, sec)
+	messages := make([]string, 0)
+
+	for _, action := range actions {
+		if m := renameTableRegexp.FindStringSubmatch(action); m != nil {
+			messages = append(messages, fmt.Sprintf("table %s is renamed to %s, code using the old name fails", table, m[1]))
+			continue
+		}
+
+		if renameConstraintRegexp.MatchString(action) {
+			continue
+		}
+
+		if m := renameColumnRegexp.FindStringSubmatch(action); m != nil {
+			messages = append(messages, fmt.Sprintf("column %s of %s is renamed to %s, code using the old name fails", m[1], table, m[2]))
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,61 @@
+package pgm
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SqlLocation переводит позицию ошибки postgres (номер символа, начиная с 1) в строку и колонку sql
+// и возвращает текст этой строки. Если позиция вне sql, возвращает нули и пустую строку.
+func SqlLocation(sql string, position int) (int, int, string) {
+	if position < 1 || position > utf8.RuneCountInString(sql) {
+		return 0, 0, ""
+	}
+
+	line, column := 1, 1
+	lineStart := 0
+	chars := 0
+	for i, r := range sql {
+		chars++
+		if chars == position {
+			break
+		}
+		if r == '\n' {
+			line++
+			column = 1
+			lineStart = i + 1
+			continue
+		}
+		column++
+	}
+
+	lineEnd := strings.IndexByte(sql[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(sql)
+	} else {
+		lineEnd += lineStart
+	}
+
+	return line, column, strings.TrimRight(sql[lineStart:lineEnd], "\r")
+}
+
+// Snippet форматирует строку sql с номером строки и указателем ^ на колонку
+func Snippet(text string, line int, column int) string {
+	prefix := fmt.Sprintf("%4d | ", line)
+	caretPad := strings.Repeat(" ", len(prefix)-2) + "| "
+
+	var pad strings.Builder
+	for i, r := range []rune(text) {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+
+	return prefix + text + "\n" + caretPad + pad.String() + "^"
+}
@@ -0,0 +1,58 @@
+package pgm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SqlLocation(t *testing.T) {
+	sql := "CREATE TABLE users (id INT);\n-- комментарий\nCREATE TABL orders (id INT);\n"
+
+	t.Run("should find line and column of position", func(t *testing.T) {
+		line, column, text := SqlLocation(sql, 52)
+		assert.Equal(t, 3, line)
+		assert.Equal(t, 8, column)
+		assert.Equal(t, "CREATE TABL orders (id INT);", text)
+		assert.Equal(t, "   3 | CREATE TABL orders (id INT);\n     |        ^", Snippet(text, line, column))
+	})
+
+	t.Run("should find first character", func(t *testing.T) {
+		line, column, _ := SqlLocation(sql, 1)
+		assert.Equal(t, 1, line)
+		assert.Equal(t, 1, column)
+	})
+
+	t.Run("should ignore position outside of sql", func(t *testing.T) {
+		line, column, text := SqlLocation(sql, 1000)
+		assert.Equal(t, 0, line)
+		assert.Equal(t, 0, column)
+		assert.Equal(t, "", text)
+	})
+}
+
+func Test_MigrationError(t *testing.T) {
+	err := &MigrationError{
+		Name:       "1_a",
+		Action:     APPLY,
+		SQLState:   "23505",
+		File:       "migrations/1_a.sql",
+		Line:       12,
+		Column:     3,
+		Snippet:    Snippet("INSERT INTO users VALUES (1);", 12, 3),
+		Detail:     "Key (id)=(1) already exists.",
+		Schema:     "public",
+		Table:      "users",
+		Constraint: "users_pkey",
+		Err:        errors.New("duplicate key value violates unique constraint \"users_pkey\""),
+	}
+
+	assert.Equal(t, "apply 1_a migration error (SQLSTATE 23505): duplicate key value violates unique constraint \"users_pkey\"\n"+
+		"  at migrations/1_a.sql:12:3\n"+
+		"  detail: Key (id)=(1) already exists.\n"+
+		"  table: public.users\n"+
+		"  constraint: users_pkey\n"+
+		"  12 | INSERT INTO users VALUES (1);\n"+
+		"     |   ^", err.Error())
+}
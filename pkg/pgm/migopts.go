@@ -2,6 +2,8 @@ package pgm
 
 import (
 	"fmt"
+	"io/fs"
+	"time"
 )
 
 type Priority string
@@ -17,6 +19,23 @@ const (
 	CREATE  Command = "create"
 	MIGRATE Command = "migrate"
 	DOWN    Command = "down"
+	// DUMP сохраняет снимок схемы базы данных или сверяет его с сохраненным
+	DUMP Command = "dump"
+	// DIFF сравнивает схемы двух баз данных
+	DIFF Command = "diff"
+	// VERIFY проверяет, что откат каждой миграции возвращает схему к состоянию до ее применения
+	VERIFY Command = "verify"
+	// SQUASH объединяет миграции, предшествующие заданной, в базовую миграцию
+	SQUASH Command = "squash"
+	// LINT проверяет sql миграций на опасные операции
+	LINT Command = "lint"
+	// PROTECT и UNPROTECT отмечают базу данных как защищенную от отката миграций и снимают отметку
+	PROTECT   Command = "protect"
+	UNPROTECT Command = "unprotect"
+	// BACKUPS выводит резервные копии таблиц, сделанные перед откатом миграций
+	BACKUPS Command = "backups"
+	// PURGE удаляет старые резервные копии таблиц
+	PURGE Command = "purge"
 )
 
 type MigratorOptions struct {
@@ -27,6 +46,87 @@ type MigratorOptions struct {
 	MigrationsTableSchema string
 	MigrationsTable       string
 	ConnectionString      string
+	// Recursive включает поиск миграций в поддиректориях MigrationsDir
+	Recursive bool
+	// Format формат создаваемой миграции
+	Format MigrationFormat
+	// FromUp файл с up sql, по которому create генерирует down sql
+	FromUp string
+	// MigrationsFS файловая система с миграциями. Если задана, используется вместо MigrationsDir
+	MigrationsFS fs.FS
+	// LockTimeout время ожидания блокировки таблицы миграций, 0 - ждать бесконечно
+	LockTimeout time.Duration
+	// SplitStatements выполнять sql миграций по одному запросу
+	SplitStatements bool
+	// StatementTimeout ограничение времени выполнения запроса миграции, 0 - без ограничения
+	StatementTimeout time.Duration
+	// Vars значения плейсхолдеров ${name} в sql миграций
+	Vars map[string]string
+	// Tenants схемы тенантов, в каждую из которых применяются миграции
+	Tenants []string
+	// TenantsQuery sql запрос, первая колонка которого возвращает схемы тенантов
+	TenantsQuery string
+	// TenantsPattern LIKE шаблон имен схем тенантов
+	TenantsPattern string
+	// Parallel количество одновременно мигрируемых тенантов
+	Parallel int
+	// OnError поведение при ошибке миграции тенанта
+	OnError ErrorPolicy
+	// ConnectionStrings строки подключения баз данных, в каждую из которых применяются миграции
+	ConnectionStrings []string
+	// ConnectionStringsFile файл со строками подключения, по одной на строку
+	ConnectionStringsFile string
+	// Canary сначала мигрировать первую базу данных и только при успехе остальные
+	Canary bool
+	// Output формат вывода результата команды
+	Output OutputFormat
+	// EchoSql выводить в лог каждый запрос перед выполнением
+	EchoSql bool
+	// Schemas схемы, попадающие в снимок схемы или сравнение. Если не заданы - все пользовательские схемы
+	Schemas []string
+	// SchemaFile файл снимка схемы
+	SchemaFile string
+	// Check сверить снимок схемы с файлом вместо его записи
+	Check bool
+	// Source и Target строки подключения сравниваемых баз данных
+	Source string
+	Target string
+	// Before миграция, все предшествующие которой объединяются командой squash
+	Before string
+	// ArchiveDir директория, в которую переносятся объединенные миграции. Если не задана, они удаляются
+	ArchiveDir string
+	// LintRules важность правил проверки миграций: имя правила - error, warning или off
+	LintRules map[string]string
+	// Pending проверять только миграции, которые еще не применены к базе данных ConnectionString
+	Pending bool
+	// Protected считать базу данных защищенной независимо от отметки в базе данных
+	Protected bool
+	// AllowRevert разрешает откат миграций защищенной базы данных без подтверждения
+	AllowRevert bool
+	// MaxReverts максимальное количество миграций, откатываемых за запуск, 0 - без ограничения
+	MaxReverts int
+	// MaxLostRows максимальное примерное количество строк, уничтожаемых откатом миграций, 0 - без ограничения
+	MaxLostRows int64
+	// ForceDataLoss выполнять откат независимо от оценки уничтожаемых данных
+	ForceDataLoss bool
+	// BackupOnRevert копировать таблицы, которые изменит откат миграций, в схему резервных копий перед откатом
+	BackupOnRevert bool
+	// OlderThan возраст резервных копий, удаляемых командой purge, 0 - все копии
+	OlderThan time.Duration
+	// ConfirmRevert запрашивает подтверждение отката миграций names перед их откатом командой migrate
+	ConfirmRevert func(names []string) bool `json:"-"`
+	// OnPlan получает шаги, которые выполнит команда migrate, вычисленные под блокировкой таблицы миграций
+	OnPlan func(steps []PlanStep) `json:"-"`
+}
+
+// IsFanOutMode миграции применяются к нескольким базам данных
+func (o *MigratorOptions) IsFanOutMode() bool {
+	return len(o.ConnectionStrings) > 0 || o.ConnectionStringsFile != ""
+}
+
+// IsTenantMode миграции применяются к схемам тенантов, а не к одной схеме
+func (o *MigratorOptions) IsTenantMode() bool {
+	return len(o.Tenants) > 0 || o.TenantsQuery != "" || o.TenantsPattern != ""
 }
 
 func (o *MigratorOptions) MigrationsTableNameWithSchema() string {
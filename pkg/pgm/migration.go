@@ -1,7 +1,30 @@
 package pgm
 
+import (
+	"io/fs"
+)
+
+type MigrationFormat string
+
+const (
+	// PAIR миграция из пары файлов {name}.up.sql и {name}.down.sql
+	PAIR MigrationFormat = "pair"
+	// SINGLE миграция из одного файла {name}.sql с секциями "-- pgm:up" и "-- pgm:down"
+	SINGLE MigrationFormat = "single"
+	// REPEATABLE повторяемая миграция R_{name}.sql, применяется заново при изменении контрольной суммы
+	REPEATABLE MigrationFormat = "repeatable"
+)
+
 type Migration struct {
-	Name string `json:"name"`
-	Up   string `json:"up"`
-	Down string `json:"down"`
+	Name   string          `json:"name"`
+	Up     string          `json:"up"`
+	Down   string          `json:"down"`
+	Format MigrationFormat `json:"format,omitempty"`
+	// UpFunc & DownFunc заполнены только для go миграций
+	UpFunc   MigrationFunc `json:"-"`
+	DownFunc MigrationFunc `json:"-"`
+	// FS файловая система, из которой читаются Up & Down. Если nil, файлы читаются с диска
+	FS fs.FS `json:"-"`
+	// Squashed миграции, объединенные в эту базовую миграцию командой squash
+	Squashed []SquashedMigration `json:"squashed,omitempty"`
 }
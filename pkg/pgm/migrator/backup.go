@@ -0,0 +1,151 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+	"github.com/quadgod/pgm/pkg/pgm/ddl"
+)
+
+// backupsTbl возвращает имя таблицы учета резервных копий со схемой
+func (m *Migrator) backupsTbl() string {
+	return m.migTbl() + db.BackupsTableSuffix
+}
+
+// backupReverts копирует таблицы, которые изменит сохраненный down sql миграций names, в новую схему резервных копий.
+// Каждая таблица копируется один раз до выполнения откатов. Go миграции не анализируются.
+// Возвращает копии по именам миграций для результатов отката.
+func (m *Migrator) backupReverts(
+	ctx context.Context,
+	tx pgx.Tx,
+	applied []pgm.AppliedMigration,
+	names []string,
+) (map[string][]pgm.Backup, error) {
+	if !m.backupOnRevert || len(names) == 0 {
+		return nil, nil
+	}
+
+	appliedByName := make(map[string]pgm.AppliedMigration, len(applied))
+	for _, a := range applied {
+		appliedByName[a.Name] = a
+	}
+
+	var backupSchema string
+	copied := make(map[string]bool)
+	backups := make(map[string][]pgm.Backup)
+
+	for _, name := range names {
+		a, found := appliedByName[name]
+		if !found || a.IsGo() {
+			continue
+		}
+
+		tables, err := ddl.AlteredTables(a.DownSql)
+		if err != nil {
+			return nil, fmt.Errorf("analyse down sql of %s migration error: %w", name, err)
+		}
+
+		for _, t := range tables {
+			schema, table, found, err := db.ResolveTable(ctx, tx, t)
+			if err != nil {
+				return nil, fmt.Errorf("resolve table %s error: %w", t, err)
+			}
+			if !found || copied[schema+"."+table] {
+				continue
+			}
+			copied[schema+"."+table] = true
+
+			if backupSchema == "" {
+				if err = db.EnsureBackupsTable(ctx, tx, m.backupsTbl()); err != nil {
+					return nil, fmt.Errorf("ensure backups table error: %w", err)
+				}
+
+				if backupSchema, err = db.CreateBackupSchema(ctx, tx, time.Now()); err != nil {
+					return nil, fmt.Errorf("create backup schema error: %w", err)
+				}
+			}
+
+			backup, err := db.BackupTable(ctx, tx, m.backupsTbl(), backupSchema, schema, table, name)
+			if err != nil {
+				return nil, fmt.Errorf("backup table %s.%s error: %w", schema, table, err)
+			}
+
+			backups[name] = append(backups[name], *backup)
+		}
+	}
+
+	return backups, nil
+}
+
+// Backups возвращает резервные копии таблиц, сделанные перед откатом миграций
+func (m *Migrator) Backups(ctx context.Context) ([]pgm.Backup, error) {
+	tx, err := m.conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	return db.GetBackups(ctx, tx, m.backupsTbl())
+}
+
+// expiredBackups возвращает копии схем резервных копий, последняя копия которых сделана раньше cutoff
+func expiredBackups(backups []pgm.Backup, cutoff time.Time) []pgm.Backup {
+	latest := make(map[string]time.Time)
+	for _, b := range backups {
+		if b.CreatedAt.After(latest[b.Schema]) {
+			latest[b.Schema] = b.CreatedAt
+		}
+	}
+
+	expired := make([]pgm.Backup, 0)
+	for _, b := range backups {
+		if latest[b.Schema].Before(cutoff) {
+			expired = append(expired, b)
+		}
+	}
+
+	return expired
+}
+
+// PurgeBackups удаляет схемы резервных копий, сделанных раньше olderThan назад, и возвращает удаленные копии.
+// С нулевым olderThan удаляются все резервные копии.
+func (m *Migrator) PurgeBackups(ctx context.Context, olderThan time.Duration) ([]pgm.Backup, error) {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	backups, err := db.GetBackups(ctx, tx, m.backupsTbl())
+	if err != nil {
+		return nil, err
+	}
+
+	purged := expiredBackups(backups, time.Now().Add(-olderThan))
+
+	dropped := make(map[string]bool)
+	for _, b := range purged {
+		if dropped[b.Schema] {
+			continue
+		}
+		dropped[b.Schema] = true
+
+		if err = db.DropBackupSchema(ctx, tx, m.backupsTbl(), b.Schema); err != nil {
+			return nil, fmt.Errorf("drop backup schema %s error: %w", b.Schema, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction error: %w", err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,27 @@
+package migrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_expiredBackups(t *testing.T) {
+	now := time.Now()
+	backups := []pgm.Backup{
+		{Schema: "pgm_backup_a", Table: "public__orders", CreatedAt: now.Add(-48 * time.Hour)},
+		{Schema: "pgm_backup_b", Table: "public__orders", CreatedAt: now.Add(-48 * time.Hour)},
+		{Schema: "pgm_backup_b", Table: "public__users", CreatedAt: now.Add(-time.Hour)},
+		{Schema: "pgm_backup_c", Table: "public__orders", CreatedAt: now},
+	}
+
+	t.Run("should expire schemas by their latest backup", func(t *testing.T) {
+		assert.Equal(t, backups[:1], expiredBackups(backups, now.Add(-24*time.Hour)))
+	})
+
+	t.Run("should expire all backups with zero age", func(t *testing.T) {
+		assert.Equal(t, backups, expiredBackups(backups, now.Add(time.Nanosecond)))
+	})
+}
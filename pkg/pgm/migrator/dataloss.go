@@ -0,0 +1,102 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+	"github.com/quadgod/pgm/pkg/pgm/ddl"
+)
+
+// destructions возвращает запросы сохраненного down sql миграций names, уничтожающие данные.
+// Go миграции не анализируются.
+func destructions(applied []pgm.AppliedMigration, names []string) ([]pgm.DataLoss, error) {
+	appliedByName := make(map[string]pgm.AppliedMigration, len(applied))
+	for _, a := range applied {
+		appliedByName[a.Name] = a
+	}
+
+	losses := make([]pgm.DataLoss, 0)
+	for _, name := range names {
+		a, found := appliedByName[name]
+		if !found || a.IsGo() {
+			continue
+		}
+
+		statements, err := ddl.Destructive(a.DownSql)
+		if err != nil {
+			return nil, fmt.Errorf("analyse down sql of %s migration error: %w", name, err)
+		}
+
+		for _, d := range statements {
+			losses = append(losses, pgm.DataLoss{Migration: name, Table: d.Table, Column: d.Column, Line: d.Line})
+		}
+	}
+
+	return losses, nil
+}
+
+// estimateDataLoss оценивает данные, которые уничтожит откат миграций names.
+// Таблицы, которых нет в базе данных, пропускаются.
+func (m *Migrator) estimateDataLoss(ctx context.Context, tx pgx.Tx, applied []pgm.AppliedMigration, names []string) ([]pgm.DataLoss, error) {
+	candidates, err := destructions(applied, names)
+	if err != nil {
+		return nil, err
+	}
+
+	losses := make([]pgm.DataLoss, 0, len(candidates))
+	for _, l := range candidates {
+		found, rows, bytes, err := db.EstimateDataLoss(ctx, tx, l.Table, l.Column)
+		if err != nil {
+			return nil, fmt.Errorf("estimate data loss of %s error: %w", l.Object(), err)
+		}
+		if !found {
+			continue
+		}
+
+		l.Rows, l.Bytes = rows, bytes
+		losses = append(losses, l)
+	}
+
+	return losses, nil
+}
+
+// checkDataLoss проверяет, что откат уничтожит не больше maxLostRows строк.
+// С forceDataLoss или нулевым maxLostRows оценка только попадает в результаты.
+func (m *Migrator) checkDataLoss(losses []pgm.DataLoss) error {
+	if m.forceDataLoss || m.maxLostRows <= 0 {
+		return nil
+	}
+
+	if rows, _ := pgm.TotalDataLoss(losses); rows > m.maxLostRows {
+		return &pgm.DataLossError{Losses: losses, MaxLostRows: m.maxLostRows}
+	}
+
+	return nil
+}
+
+// guardDataLoss оценивает данные, которые уничтожит откат миграций names, и проверяет ограничение.
+// Возвращает оценки по именам миграций для результатов отката.
+func (m *Migrator) guardDataLoss(ctx context.Context, tx pgx.Tx, applied []pgm.AppliedMigration, names []string) (map[string][]pgm.DataLoss, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	losses, err := m.estimateDataLoss(ctx, tx, applied, names)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.checkDataLoss(losses); err != nil {
+		return nil, err
+	}
+
+	byMigration := make(map[string][]pgm.DataLoss)
+	for _, l := range losses {
+		byMigration[l.Migration] = append(byMigration[l.Migration], l)
+	}
+
+	return byMigration, nil
+}
@@ -0,0 +1,45 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_destructions(t *testing.T) {
+	applied := []pgm.AppliedMigration{
+		{Name: "1_a", Kind: "sql", DownSql: "DROP TABLE public.users;"},
+		{Name: "2_b", Kind: string(pgm.GO)},
+		{Name: "3_c", Kind: "sql", DownSql: "DROP INDEX users_email;\nALTER TABLE public.users DROP COLUMN note;"},
+	}
+
+	losses, err := destructions(applied, []string{"3_c", "2_b"})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []pgm.DataLoss{{Migration: "3_c", Table: "public.users", Column: "note", Line: 2}}, losses)
+}
+
+func Test_checkDataLoss(t *testing.T) {
+	losses := []pgm.DataLoss{
+		{Migration: "3_c", Table: "public.users", Column: "note", Rows: 600},
+		{Migration: "1_a", Table: "public.users", Rows: 600},
+	}
+
+	t.Run("should only report without limit", func(t *testing.T) {
+		m := &Migrator{}
+		assert.Nil(t, m.checkDataLoss(losses))
+	})
+
+	t.Run("should refuse revert over the limit", func(t *testing.T) {
+		m := &Migrator{maxLostRows: 1000}
+		err := m.checkDataLoss(losses)
+		assert.ErrorIs(t, err, pgm.ErrRevertRefused)
+		assert.EqualError(t, err, "revert would destroy about 1200 rows (0 B), max lost rows is 1000, pass force data loss to revert. "+
+			"data loss: 3_c column public.users.note ~600 rows, 1_a table public.users ~600 rows")
+
+		m.forceDataLoss = true
+		assert.Nil(t, m.checkDataLoss(losses))
+	})
+}
@@ -0,0 +1,44 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+// withGoMigrations добавляет go миграции к миграциям из файловой системы с сохранением порядка по имени
+func withGoMigrations(fsMigrations []pgm.Migration, goMigrations []pgm.Migration) ([]pgm.Migration, error) {
+	names := make(map[string]struct{}, len(fsMigrations))
+	for _, m := range fsMigrations {
+		names[m.Name] = struct{}{}
+	}
+
+	migrations := make([]pgm.Migration, 0, len(fsMigrations)+len(goMigrations))
+	migrations = append(migrations, fsMigrations...)
+
+	for _, m := range goMigrations {
+		if _, found := names[m.Name]; found {
+			return nil, fmt.Errorf("go migration \"%s\" conflicts with migration file of the same name", m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+
+	return migrations, nil
+}
+
+// goMigrationsByName индексирует go миграции из списка по имени
+func goMigrationsByName(migrations []pgm.Migration) map[string]pgm.Migration {
+	byName := make(map[string]pgm.Migration)
+	for _, m := range migrations {
+		if m.Format == pgm.GO {
+			byName[m.Name] = m
+		}
+	}
+
+	return byName
+}
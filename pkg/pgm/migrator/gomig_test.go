@@ -0,0 +1,45 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_withGoMigrations(t *testing.T) {
+	noop := func(ctx context.Context, tx pgx.Tx) error { return nil }
+
+	t.Run("should interleave go migrations with file migrations by name", func(t *testing.T) {
+		fsMigrations := []pgm.Migration{
+			{Name: "1_init", Format: pgm.PAIR},
+			{Name: "3_users", Format: pgm.SINGLE},
+		}
+		goMigrations := []pgm.Migration{
+			{Name: "2_backfill", Format: pgm.GO, UpFunc: noop},
+			{Name: "4_reencrypt", Format: pgm.GO, UpFunc: noop},
+		}
+
+		migrations, err := withGoMigrations(fsMigrations, goMigrations)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		names := make([]string, 0, len(migrations))
+		for _, m := range migrations {
+			names = append(names, m.Name)
+		}
+		assert.Equal(t, []string{"1_init", "2_backfill", "3_users", "4_reencrypt"}, names)
+	})
+
+	t.Run("should not merge because go migration has the same name as file migration", func(t *testing.T) {
+		fsMigrations := []pgm.Migration{{Name: "1_init", Format: pgm.PAIR}}
+		goMigrations := []pgm.Migration{{Name: "1_init", Format: pgm.GO, UpFunc: noop}}
+
+		migrations, err := withGoMigrations(fsMigrations, goMigrations)
+		assert.Nil(t, migrations)
+		assert.EqualError(t, err, "go migration \"1_init\" conflicts with migration file of the same name")
+	})
+}
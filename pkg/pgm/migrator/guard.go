@@ -0,0 +1,78 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+)
+
+// reverts возвращает имена миграций, которые откатывают шаги плана
+func reverts(steps []pgm.PlanStep) []string {
+	names := make([]string, 0)
+	for _, step := range steps {
+		if step.Action == pgm.REVERT {
+			names = append(names, step.Name)
+		}
+	}
+	return names
+}
+
+// guardReverts проверяет, что откат миграций names разрешен: количество не превышает maxReverts,
+// откат защищенной базы данных разрешен allowRevert, а запрос подтверждения confirm, если задан, подтвержден.
+// С allowRevert подтверждение не запрашивается.
+func (m *Migrator) guardReverts(ctx context.Context, tx pgx.Tx, names []string, confirm func(names []string) bool) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	if m.maxReverts > 0 && len(names) > m.maxReverts {
+		return &pgm.RevertRefusedError{Names: names, Reason: fmt.Sprintf("max reverts is %d", m.maxReverts)}
+	}
+
+	if m.allowRevert {
+		return nil
+	}
+
+	protected := m.protected
+	if !protected {
+		var err error
+		if protected, err = db.IsProtected(ctx, tx, m.migTbl()); err != nil {
+			return fmt.Errorf("read protection error: %w", err)
+		}
+	}
+
+	if protected {
+		return &pgm.RevertRefusedError{Names: names, Reason: "database is protected, pass allow revert to revert migrations"}
+	}
+
+	if confirm != nil && !confirm(names) {
+		return &pgm.RevertRefusedError{Names: names, Reason: "revert was not confirmed"}
+	}
+
+	return nil
+}
+
+// Protect отмечает базу данных как защищенную от отката миграций или снимает отметку.
+// Откат миграций защищенной базы данных выполняется только с WithAllowRevert.
+func (m *Migrator) Protect(ctx context.Context, protected bool) error {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err = db.SetProtected(ctx, tx, m.migTbl(), protected); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction error: %w", err)
+	}
+
+	return nil
+}
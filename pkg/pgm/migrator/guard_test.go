@@ -0,0 +1,35 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_guardReverts(t *testing.T) {
+	ctx := context.Background()
+	confirmed := func([]string) bool { return true }
+
+	t.Run("should allow runs without reverts", func(t *testing.T) {
+		m := &Migrator{protected: true, maxReverts: 1}
+		assert.Nil(t, m.guardReverts(ctx, nil, reverts([]pgm.PlanStep{{Name: "1_a", Action: pgm.APPLY}}), nil))
+	})
+
+	t.Run("should refuse reverts over the limit", func(t *testing.T) {
+		m := &Migrator{allowRevert: true, maxReverts: 1}
+		err := m.guardReverts(ctx, nil, []string{"3_c", "2_b"}, confirmed)
+		assert.ErrorIs(t, err, pgm.ErrRevertRefused)
+		assert.EqualError(t, err, "revert of 2 migrations refused: max reverts is 1. migrations: 3_c, 2_b")
+	})
+
+	t.Run("should refuse reverts of protected database", func(t *testing.T) {
+		m := &Migrator{protected: true}
+		err := m.guardReverts(ctx, nil, []string{"2_b"}, confirmed)
+		assert.ErrorIs(t, err, pgm.ErrRevertRefused)
+
+		m.allowRevert = true
+		assert.Nil(t, m.guardReverts(ctx, nil, []string{"2_b"}, func([]string) bool { return false }))
+	})
+}
@@ -0,0 +1,804 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+	pgmfs "github.com/quadgod/pgm/pkg/pgm/fs"
+	"github.com/quadgod/pgm/pkg/pgm/psql"
+)
+
+const lockNotAvailable = "55P03"
+
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Migrator применяет и откатывает миграции на переданном соединении.
+// Соединение не закрывается мигратором.
+type Migrator struct {
+	conn             db.Conn
+	dir              string
+	fsys             fs.FS
+	recursive        bool
+	goMigrations     []pgm.Migration
+	priority         pgm.Priority
+	tableSchema      string
+	table            string
+	lockTimeout      time.Duration
+	warn             func(warning string)
+	hooks            map[pgm.HookEvent][]pgm.HookFunc
+	vars             map[string]string
+	searchPath       []string
+	splitStatements  bool
+	statementTimeout time.Duration
+	protected        bool
+	allowRevert      bool
+	maxReverts       int
+	confirmRevert    func(names []string) bool
+	maxLostRows      int64
+	forceDataLoss    bool
+	backupOnRevert   bool
+	onPlan           func(steps []pgm.PlanStep)
+}
+
+// revertRun оценки потери данных и резервные копии таблиц запуска по именам откатываемых миграций
+type revertRun struct {
+	dataLoss map[string][]pgm.DataLoss
+	backups  map[string][]pgm.Backup
+}
+
+// newRevertRun проверяет оценку потери данных отката миграций names и копирует изменяемые ими таблицы
+func (m *Migrator) newRevertRun(ctx context.Context, tx pgx.Tx, applied []pgm.AppliedMigration, names []string) (*revertRun, error) {
+	dataLoss, err := m.guardDataLoss(ctx, tx, applied, names)
+	if err != nil {
+		return nil, err
+	}
+
+	backups, err := m.backupReverts(ctx, tx, applied, names)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revertRun{dataLoss: dataLoss, backups: backups}, nil
+}
+
+// New создает мигратор. conn может быть *pgxpool.Pool или *pgx.Conn.
+func New(conn db.Conn, opts ...Option) (*Migrator, error) {
+	if conn == nil {
+		return nil, &pgm.ValidationError{Message: "database connection is required"}
+	}
+
+	m := &Migrator{
+		conn:         conn,
+		goMigrations: pgm.GoMigrations(),
+		priority:     pgm.DB,
+		tableSchema:  "public",
+		table:        "migrations",
+		warn:         func(string) {},
+		hooks:        make(map[pgm.HookEvent][]pgm.HookFunc),
+		vars:         make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	switch m.priority {
+	case pgm.DB, pgm.FS:
+		break
+	default:
+		return nil, &pgm.ValidationError{Message: fmt.Sprintf("invalid priority. valid values \"%s\" or \"%s\"", pgm.DB, pgm.FS)}
+	}
+
+	if !identifierRegexp.MatchString(m.tableSchema) {
+		return nil, &pgm.ValidationError{Message: "migrations table schema might contain only letters, numbers and \"_\" symbol"}
+	}
+
+	if !identifierRegexp.MatchString(m.table) {
+		return nil, &pgm.ValidationError{Message: "migrations table might contain only letters, numbers and \"_\" symbol"}
+	}
+
+	return m, nil
+}
+
+func (m *Migrator) migTbl() string {
+	return fmt.Sprintf("%s.%s", m.tableSchema, m.table)
+}
+
+// source читает миграции и колбэки из директории или файловой системы, списки миграций, объединенных в базовые миграции,
+// и добавляет к миграциям go миграции
+func (m *Migrator) source() (*pgmfs.Source, error) {
+	var source *pgmfs.Source
+	var err error
+
+	switch {
+	case m.fsys != nil:
+		source, err = pgmfs.ReadSourceFS(m.fsys, pgmfs.ReadOptions{Recursive: m.recursive})
+	case m.dir != "":
+		source, err = pgmfs.ReadSource(m.dir, pgmfs.ReadOptions{Recursive: m.recursive})
+	default:
+		source = &pgmfs.Source{
+			Migrations: make([]pgm.Migration, 0),
+			Callbacks:  make(map[pgm.HookEvent]pgm.Callback),
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ignored := range source.Ignored {
+		m.warn(fmt.Sprintf("file \"%s\" is not a migration and was ignored", ignored))
+	}
+
+	for i, migration := range source.Migrations {
+		squashed, readErr := pgmfs.ReadSquashed(migration)
+		if readErr != nil {
+			return nil, fmt.Errorf("read squashed migrations of %s error: %w", migration.Name, readErr)
+		}
+		if len(squashed) > 0 {
+			source.Migrations[i].Squashed = squashed
+		}
+	}
+
+	source.Migrations, err = withGoMigrations(source.Migrations, m.goMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// readRepeatables читает sql повторяемых миграций и вычисляет их контрольные суммы.
+// Контрольная сумма считается по sql с подключенными файлами и подставленными плейсхолдерами,
+// поэтому изменение подключенного файла или значения плейсхолдера применяет миграцию заново.
+func (m *Migrator) readRepeatables(source *pgmfs.Source) ([]repeatable, error) {
+	repeatables := make([]repeatable, 0, len(source.Repeatables))
+	for _, migration := range source.Repeatables {
+		upSql, _, err := pgmfs.ReadMigrationSql(migration)
+		if err != nil {
+			return nil, err
+		}
+
+		expandedSql, lines, err := m.expandPsql(migration.FS, migration.Up, upSql)
+		if err != nil {
+			return nil, migrationError(migration.Name, pgm.APPLY, err)
+		}
+
+		effectiveSql, err := pgm.Substitute(expandedSql, m.vars)
+		if err != nil {
+			return nil, migrationError(migration.Name, pgm.APPLY, err)
+		}
+
+		repeatables = append(repeatables, repeatable{
+			migration: migration,
+			sql:       upSql,
+			expanded:  expandedSql,
+			lines:     lines,
+			checksum:  pgm.Checksum(effectiveSql),
+		})
+	}
+
+	return repeatables, nil
+}
+
+// expandPsql разворачивает мета-команды psql в sql, прочитанном из файла filePath файловой системы fsys,
+// и возвращает карту строк развернутого sql. \i ищет файлы от корня директории миграций, \ir и \copy - от директории файла.
+func (m *Migrator) expandPsql(fsys fs.FS, filePath string, sql string) (string, psql.SourceMap, error) {
+	root := m.dir
+	if fsys != nil {
+		root = "."
+	}
+
+	return psql.Expand(sql, psql.Options{FS: fsys, Root: root, Path: filePath})
+}
+
+// runHooks выполняет sql колбэк из директории миграций и go колбэки события
+func (m *Migrator) runHooks(ctx context.Context, tx pgx.Tx, source *pgmfs.Source, info pgm.HookInfo) error {
+	if callback, found := source.Callbacks[info.Event]; found {
+		callbackSql, err := pgmfs.ReadCallbackSql(callback)
+		if err != nil {
+			return &pgm.HookError{Event: info.Event, MigrationName: info.MigrationName, Err: err}
+		}
+
+		callbackSql, _, err = m.expandPsql(callback.FS, callback.Path, callbackSql)
+		if err != nil {
+			return &pgm.HookError{Event: info.Event, MigrationName: info.MigrationName, Err: err}
+		}
+
+		callbackSql, err = pgm.Substitute(callbackSql, m.vars)
+		if err != nil {
+			return &pgm.HookError{Event: info.Event, MigrationName: info.MigrationName, Err: err}
+		}
+
+		if _, err = db.ExecSql(ctx, tx, callbackSql, db.ExecOptions{}); err != nil {
+			return &pgm.HookError{Event: info.Event, MigrationName: info.MigrationName, Err: err}
+		}
+	}
+
+	for _, hook := range m.hooks[info.Event] {
+		if err := hook(ctx, tx, info); err != nil {
+			return &pgm.HookError{Event: info.Event, MigrationName: info.MigrationName, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// begin создает таблицу миграций, открывает транзакцию и блокирует таблицу миграций
+func (m *Migrator) begin(ctx context.Context) (pgx.Tx, error) {
+	err := db.EnsureMigrationsTable(ctx, m.conn, m.tableSchema, m.table)
+	if err != nil {
+		return nil, fmt.Errorf("ensure migrations table error: %w", err)
+	}
+
+	tx, err := m.conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.searchPath) > 0 {
+		if err = db.SetSearchPath(ctx, tx, m.searchPath); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, fmt.Errorf("set search path error: %w", err)
+		}
+	}
+
+	err = db.LockMigrationsTableWithTimeout(ctx, tx, m.migTbl(), m.lockTimeout)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == lockNotAvailable {
+			return nil, &pgm.LockTimeoutError{Table: m.migTbl(), Err: err}
+		}
+
+		return nil, fmt.Errorf("lock migrations table error: %w", err)
+	}
+
+	return tx, nil
+}
+
+// readApplied читает примененные версионные и повторяемые миграции без создания таблицы миграций
+func (m *Migrator) readApplied(ctx context.Context) ([]pgm.AppliedMigration, []pgm.AppliedMigration, error) {
+	tx, err := m.conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	exists, err := db.MigrationsTableExists(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !exists {
+		return make([]pgm.AppliedMigration, 0), make([]pgm.AppliedMigration, 0), nil
+	}
+
+	applied, err := db.GetMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	appliedRepeatables, err := db.GetRepeatableMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return applied, appliedRepeatables, nil
+}
+
+// errorPosition возвращает строку, колонку и текст строки выполненного sql, на которые указывает ошибка postgres.
+// При выполнении по одному запросу строка считается от начала sql миграции, а если postgres
+// не вернул позицию, указывает на начало упавшего запроса.
+func errorPosition(err error) (int, int, string) {
+	var execErr *db.ExecError
+	if !errors.As(err, &execErr) {
+		return 0, 0, ""
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Position == 0 {
+		if !execErr.Statement {
+			return 0, 0, ""
+		}
+		line, column, text := pgm.SqlLocation(execErr.Sql, 1)
+		return line + execErr.LineOffset, column, text
+	}
+
+	line, column, text := pgm.SqlLocation(execErr.Sql, int(pgErr.Position))
+	if line == 0 {
+		return 0, 0, ""
+	}
+
+	return line + execErr.LineOffset, column, text
+}
+
+// migrationError оборачивает ошибку миграции в *pgm.MigrationError и переносит в нее поля ошибки postgres.
+// Если ошибка содержит позицию в выполненном sql, заполняются строка, колонка и фрагмент sql.
+func migrationError(name string, action pgm.PlanAction, err error) *pgm.MigrationError {
+	migErr := &pgm.MigrationError{Name: name, Action: action, Err: err}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return migErr
+	}
+
+	migErr.SQLState = pgErr.Code
+	migErr.Detail = pgErr.Detail
+	migErr.Hint = pgErr.Hint
+	migErr.Schema = pgErr.SchemaName
+	migErr.Table = pgErr.TableName
+	migErr.Constraint = pgErr.ConstraintName
+
+	if line, column, text := errorPosition(err); line > 0 {
+		migErr.Line = line
+		migErr.Column = column
+		migErr.Snippet = pgm.Snippet(text, line, column)
+	}
+
+	return migErr
+}
+
+// locateInFile переводит положение ошибки в выполненном sql в положение в файле миграции filePath,
+// из которого был прочитан rawSql, или в подключенном им файле по карте строк lines развернутого sql.
+// Без lines строки выполненного sql совпадают со строками rawSql.
+// Плейсхолдеры не меняют номера строк, если их значения однострочные.
+func locateInFile(
+	migErr *pgm.MigrationError,
+	migration pgm.Migration,
+	filePath string,
+	rawSql string,
+	lines psql.SourceMap,
+) *pgm.MigrationError {
+	line, column, text := errorPosition(migErr.Err)
+	if line == 0 {
+		migErr.File = filePath
+		return migErr
+	}
+
+	if lines != nil {
+		source, found := lines.Locate(line)
+		if !found {
+			return migErr
+		}
+
+		if source.File != filePath {
+			migErr.File = source.File
+			migErr.Line = source.Line
+			migErr.Snippet = pgm.Snippet(text, source.Line, column)
+			return migErr
+		}
+
+		line = source.Line
+	}
+
+	offset, err := pgmfs.SqlLineOffset(migration, filePath, rawSql)
+	if err != nil {
+		return migErr
+	}
+
+	migErr.File = filePath
+	migErr.Line = line + offset
+	migErr.Snippet = pgm.Snippet(text, migErr.Line, column)
+
+	return migErr
+}
+
+// execOptions параметры выполнения sql миграций
+func (m *Migrator) execOptions() db.ExecOptions {
+	return db.ExecOptions{
+		Vars:             m.vars,
+		SplitStatements:  m.splitStatements,
+		StatementTimeout: m.statementTimeout,
+	}
+}
+
+// apply применяет миграцию из файловой системы или go миграцию
+func (m *Migrator) apply(
+	ctx context.Context,
+	tx pgx.Tx,
+	source *pgmfs.Source,
+	migration pgm.Migration,
+) (*pgm.MigrationResult, error) {
+	ctx = db.WithMigrationName(ctx, migration.Name)
+
+	err := m.runHooks(ctx, tx, source, pgm.HookInfo{Event: pgm.BEFORE_EACH_MIGRATE, MigrationName: migration.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	result := &pgm.MigrationResult{MigrationName: migration.Name, Status: pgm.APPLIED}
+
+	if migration.Format == pgm.GO {
+		if err := db.ApplyGoMigration(ctx, tx, migration, m.migTbl()); err != nil {
+			return nil, migrationError(migration.Name, pgm.APPLY, err)
+		}
+	} else {
+		upSql, downSql, err := pgmfs.ReadMigrationSql(migration)
+		if err != nil {
+			return nil, migrationError(migration.Name, pgm.APPLY, err)
+		}
+
+		expandedUpSql, upLines, err := m.expandPsql(migration.FS, migration.Up, upSql)
+		if err != nil {
+			return nil, migrationError(migration.Name, pgm.APPLY, err)
+		}
+
+		// down sql сохраняется с подставленными файлами, чтобы откат не зависел от их изменений
+		expandedDownSql, _, err := m.expandPsql(migration.FS, migration.Down, downSql)
+		if err != nil {
+			return nil, migrationError(migration.Name, pgm.APPLY, err)
+		}
+
+		statements, err := db.ApplyMigration(
+			ctx,
+			tx,
+			migration.Name,
+			m.migTbl(),
+			expandedUpSql,
+			expandedDownSql,
+			pgm.Checksum(upSql),
+			m.execOptions(),
+		)
+		if err != nil {
+			return nil, locateInFile(migrationError(migration.Name, pgm.APPLY, err), migration, migration.Up, upSql, upLines)
+		}
+
+		result.Checksum = pgm.Checksum(upSql)
+		result.Statements = statements
+	}
+
+	result.Duration = time.Since(started)
+
+	err = m.runHooks(ctx, tx, source, pgm.HookInfo{
+		Event:         pgm.AFTER_EACH_MIGRATE,
+		MigrationName: migration.Name,
+		Result:        result,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// applyRepeatable применяет повторяемую миграцию
+func (m *Migrator) applyRepeatable(
+	ctx context.Context,
+	tx pgx.Tx,
+	source *pgmfs.Source,
+	r repeatable,
+) (*pgm.MigrationResult, error) {
+	name := r.migration.Name
+	ctx = db.WithMigrationName(ctx, name)
+
+	err := m.runHooks(ctx, tx, source, pgm.HookInfo{Event: pgm.BEFORE_EACH_MIGRATE, MigrationName: name})
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+
+	statements, err := db.ApplyRepeatableMigration(ctx, tx, name, m.migTbl(), r.expanded, r.checksum, m.execOptions())
+	if err != nil {
+		return nil, locateInFile(migrationError(name, pgm.APPLY, err), r.migration, r.migration.Up, r.sql, r.lines)
+	}
+
+	result := &pgm.MigrationResult{
+		MigrationName: name,
+		Status:        pgm.APPLIED,
+		Duration:      time.Since(started),
+		Checksum:      r.checksum,
+		Statements:    statements,
+	}
+
+	err = m.runHooks(ctx, tx, source, pgm.HookInfo{
+		Event:         pgm.AFTER_EACH_MIGRATE,
+		MigrationName: name,
+		Result:        result,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// revertError оборачивает ошибку отката. Если миграция есть в директории миграций
+// и сохраненный down sql совпадает с down sql файла после подключения файлов и подстановки
+// плейсхолдеров, ошибка указывает на строку файла, иначе - на строку сохраненного down sql.
+func (m *Migrator) revertError(source *pgmfs.Source, name string, err error) error {
+	migErr := migrationError(name, pgm.REVERT, err)
+
+	var revertErr *db.RevertError
+	if !errors.As(err, &revertErr) {
+		return migErr
+	}
+
+	for _, migration := range source.Migrations {
+		if migration.Name != name || migration.Format == pgm.GO {
+			continue
+		}
+
+		_, downSql, readErr := pgmfs.ReadMigrationSql(migration)
+		if readErr != nil {
+			return migErr
+		}
+
+		expandedDownSql, lines, expandErr := m.expandPsql(migration.FS, migration.Down, downSql)
+		if expandErr != nil {
+			return migErr
+		}
+
+		resolvedDownSql, substituteErr := pgm.Substitute(expandedDownSql, m.vars)
+		if substituteErr != nil || resolvedDownSql != revertErr.DownSql {
+			return migErr
+		}
+
+		return locateInFile(migErr, migration, migration.Down, downSql, lines)
+	}
+
+	return migErr
+}
+
+// revert откатывает примененную миграцию. Оценка потери данных и резервные копии run, если задан, попадают в результат
+func (m *Migrator) revert(
+	ctx context.Context,
+	tx pgx.Tx,
+	source *pgmfs.Source,
+	name string,
+	run *revertRun,
+) (*pgm.MigrationResult, error) {
+	ctx = db.WithMigrationName(ctx, name)
+
+	err := m.runHooks(ctx, tx, source, pgm.HookInfo{Event: pgm.BEFORE_EACH_REVERT, MigrationName: name})
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+
+	result, err := db.RevertMigration(ctx, tx, m.migTbl(), name, goMigrationsByName(m.goMigrations), m.execOptions())
+	if err != nil {
+		return nil, m.revertError(source, name, err)
+	}
+
+	result.Duration = time.Since(started)
+	if run != nil {
+		result.DataLoss = run.dataLoss[name]
+		result.Backups = run.backups[name]
+	}
+
+	err = m.runHooks(ctx, tx, source, pgm.HookInfo{
+		Event:         pgm.AFTER_EACH_REVERT,
+		MigrationName: name,
+		Result:        result,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Plan возвращает шаги, которые выполнит Migrate, не изменяя базу данных
+func (m *Migrator) Plan(ctx context.Context) ([]pgm.PlanStep, error) {
+	source, err := m.source()
+	if err != nil {
+		return nil, err
+	}
+
+	repeatables, err := m.readRepeatables(source)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, appliedRepeatables, err := m.readApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := plan(m.priority, source.Migrations, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(steps, planRepeatables(repeatables, appliedRepeatables)...), nil
+}
+
+// Status возвращает состояние каждой миграции из файловой системы и базы данных
+func (m *Migrator) Status(ctx context.Context) ([]pgm.MigrationStatus, error) {
+	source, err := m.source()
+	if err != nil {
+		return nil, err
+	}
+
+	repeatables, err := m.readRepeatables(source)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, appliedRepeatables, err := m.readApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := status(source.Migrations, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(statuses, repeatableStatus(repeatables, appliedRepeatables)...), nil
+}
+
+// Migrate синхронизирует базу данных с миграциями согласно приоритету
+func (m *Migrator) Migrate(ctx context.Context) ([]pgm.MigrationResult, error) {
+	source, err := m.source()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	applied, err := db.GetMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := plan(m.priority, source.Migrations, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	repeatables, err := m.readRepeatables(source)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedRepeatables, err := db.GetRepeatableMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, err
+	}
+
+	repeatableSteps := planRepeatables(repeatables, appliedRepeatables)
+
+	// план передается до проверок отката, чтобы отчет показывал его и при отказе
+	if m.onPlan != nil {
+		m.onPlan(append(append(make([]pgm.PlanStep, 0, len(steps)+len(repeatableSteps)), steps...), repeatableSteps...))
+	}
+
+	if err = m.guardReverts(ctx, tx, reverts(steps), m.confirmRevert); err != nil {
+		return nil, err
+	}
+
+	run, err := m.newRevertRun(ctx, tx, applied, reverts(steps))
+	if err != nil {
+		return nil, err
+	}
+
+	repeatableByName := make(map[string]repeatable, len(repeatables))
+	for _, r := range repeatables {
+		repeatableByName[r.migration.Name] = r
+	}
+
+	sourceByName := make(map[string]pgm.Migration, len(source.Migrations))
+	for _, migration := range source.Migrations {
+		sourceByName[migration.Name] = migration
+	}
+
+	if err = m.runHooks(ctx, tx, source, pgm.HookInfo{Event: pgm.BEFORE_MIGRATE}); err != nil {
+		return nil, err
+	}
+
+	results := make([]pgm.MigrationResult, 0, len(steps))
+	for _, step := range steps {
+		var result *pgm.MigrationResult
+
+		switch step.Action {
+		case pgm.REVERT:
+			result, err = m.revert(ctx, tx, source, step.Name, run)
+		case pgm.APPLY:
+			result, err = m.apply(ctx, tx, source, sourceByName[step.Name])
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, *result)
+	}
+
+	for _, step := range repeatableSteps {
+		result, err := m.applyRepeatable(ctx, tx, source, repeatableByName[step.Name])
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, *result)
+	}
+
+	if err = m.runHooks(ctx, tx, source, pgm.HookInfo{Event: pgm.AFTER_MIGRATE, Results: results}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction error: %w", err)
+	}
+
+	return results, nil
+}
+
+// Down откатывает последнюю примененную миграцию
+func (m *Migrator) Down(ctx context.Context) (*pgm.MigrationResult, error) {
+	source, err := m.source()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	applied, err := db.GetMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(applied) == 0 {
+		return nil, pgm.ErrNoMigrations
+	}
+
+	// down явно запрашивает откат, поэтому подтверждение не запрашивается
+	last := applied[len(applied)-1].Name
+	if err = m.guardReverts(ctx, tx, []string{last}, nil); err != nil {
+		return nil, err
+	}
+
+	run, err := m.newRevertRun(ctx, tx, applied, []string{last})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.runHooks(ctx, tx, source, pgm.HookInfo{Event: pgm.BEFORE_REVERT}); err != nil {
+		return nil, err
+	}
+
+	result, err := m.revert(ctx, tx, source, last, run)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.runHooks(ctx, tx, source, pgm.HookInfo{
+		Event:   pgm.AFTER_REVERT,
+		Results: []pgm.MigrationResult{*result},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction error: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,195 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_locateInFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_users.sql": {Data: []byte("-- users\n-- pgm:up\nCREATE TABLE users (id INT);\nCREATE TABL orders (id INT);\n\n-- pgm:down\nDROP TABLE users;\n")},
+	}
+	migration := pgm.Migration{Name: "1_users", Up: "1_users.sql", Down: "1_users.sql", Format: pgm.SINGLE, FS: fsys}
+	upSql := "CREATE TABLE users (id INT);\nCREATE TABL orders (id INT);"
+
+	t.Run("should point to line and column of migration file", func(t *testing.T) {
+		err := &db.ExecError{Sql: upSql, Err: &pgconn.PgError{Code: "42601", Message: "syntax error at or near \"TABL\"", Position: 37}}
+
+		migErr := locateInFile(migrationError("1_users", pgm.APPLY, err), migration, migration.Up, upSql, nil)
+		assert.Equal(t, "42601", migErr.SQLState)
+		assert.Equal(t, "1_users.sql", migErr.File)
+		assert.Equal(t, 4, migErr.Line)
+		assert.Equal(t, 8, migErr.Column)
+		assert.Equal(t, "   4 | CREATE TABL orders (id INT);\n     |        ^", migErr.Snippet)
+	})
+
+	t.Run("should keep sql position when sql is not found in file", func(t *testing.T) {
+		err := &db.ExecError{Sql: upSql, Err: &pgconn.PgError{Code: "42601", Position: 37}}
+
+		migErr := locateInFile(migrationError("1_users", pgm.APPLY, err), migration, migration.Up, "SELECT 1;", nil)
+		assert.Equal(t, "", migErr.File)
+		assert.Equal(t, 2, migErr.Line)
+	})
+
+	t.Run("should point to line of failed statement", func(t *testing.T) {
+		err := &db.ExecError{
+			Sql:        "CREATE TABL orders (id INT)",
+			LineOffset: 1,
+			Statement:  true,
+			Err:        &pgconn.PgError{Code: "42601", Position: 8},
+		}
+
+		migErr := locateInFile(migrationError("1_users", pgm.APPLY, err), migration, migration.Up, upSql, nil)
+		assert.Equal(t, 4, migErr.Line)
+		assert.Equal(t, 8, migErr.Column)
+	})
+
+	t.Run("should point to start of failed statement without position", func(t *testing.T) {
+		err := &db.ExecError{
+			Sql:        "CREATE TABL orders (id INT)",
+			LineOffset: 1,
+			Statement:  true,
+			Err:        &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"},
+		}
+
+		migErr := locateInFile(migrationError("1_users", pgm.APPLY, err), migration, migration.Up, upSql, nil)
+		assert.Equal(t, 4, migErr.Line)
+		assert.Equal(t, 1, migErr.Column)
+	})
+
+	t.Run("should point to line after included file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"2_orders.up.sql": {Data: []byte("\\i inc.sql\nSELECT 1;\nSELEC broken;\n")},
+			"inc.sql":         {Data: []byte("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\nCREATE TABL c (id INT);\nSELECT 2;\n")},
+		}
+		migration := pgm.Migration{Name: "2_orders", Up: "2_orders.up.sql", Format: pgm.PAIR, FS: fsys}
+		upSql := "\\i inc.sql\nSELECT 1;\nSELEC broken;\n"
+
+		m := &Migrator{}
+		expanded, lines, err := m.expandPsql(fsys, migration.Up, upSql)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		position := strings.Index(expanded, "SELEC broken") + 1
+		execErr := &db.ExecError{Sql: expanded, Err: &pgconn.PgError{Code: "42601", Position: int32(position)}}
+		migErr := locateInFile(migrationError("2_orders", pgm.APPLY, execErr), migration, migration.Up, upSql, lines)
+		assert.Equal(t, "2_orders.up.sql", migErr.File)
+		assert.Equal(t, 3, migErr.Line)
+		assert.Equal(t, "   3 | SELEC broken;\n     | ^", migErr.Snippet)
+
+		position = strings.Index(expanded, "TABL c") + 1
+		execErr = &db.ExecError{Sql: expanded, Err: &pgconn.PgError{Code: "42601", Position: int32(position)}}
+		migErr = locateInFile(migrationError("2_orders", pgm.APPLY, execErr), migration, migration.Up, upSql, lines)
+		assert.Equal(t, "inc.sql", migErr.File)
+		assert.Equal(t, 3, migErr.Line)
+		assert.Equal(t, 8, migErr.Column)
+		assert.Equal(t, "   3 | CREATE TABL c (id INT);\n     |        ^", migErr.Snippet)
+	})
+
+	t.Run("should copy postgres error fields", func(t *testing.T) {
+		err := &db.ExecError{Sql: upSql, Err: &pgconn.PgError{
+			Code:           "23505",
+			Detail:         "Key (id)=(1) already exists.",
+			Hint:           "hint",
+			SchemaName:     "public",
+			TableName:      "users",
+			ConstraintName: "users_pkey",
+		}}
+
+		migErr := locateInFile(migrationError("1_users", pgm.APPLY, err), migration, migration.Up, upSql, nil)
+		assert.Equal(t, "1_users.sql", migErr.File)
+		assert.Equal(t, 0, migErr.Line)
+		assert.Equal(t, "Key (id)=(1) already exists.", migErr.Detail)
+		assert.Equal(t, "hint", migErr.Hint)
+		assert.Equal(t, "users", migErr.Table)
+		assert.Equal(t, "users_pkey", migErr.Constraint)
+	})
+}
+
+func Test_revertError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_users.up.sql":   {Data: []byte("CREATE TABLE ${schema}.users (id INT);\n")},
+		"1_users.down.sql": {Data: []byte("SELECT 1;\nDROP TABL ${schema}.users;\n")},
+	}
+
+	m := &Migrator{fsys: fsys, vars: map[string]string{"schema": "app"}, warn: func(string) {}}
+	source, err := m.source()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	revertError := func(downSql string) *pgm.MigrationError {
+		position := strings.Index(downSql, "TABL") + 1
+		execErr := &db.ExecError{Sql: downSql, Err: &pgconn.PgError{Code: "42601", Position: int32(position)}}
+		err := m.revertError(source, "1_users", &db.RevertError{DownSql: downSql, Err: execErr})
+
+		var migErr *pgm.MigrationError
+		if !assert.ErrorAs(t, err, &migErr) {
+			t.FailNow()
+		}
+		return migErr
+	}
+
+	t.Run("should point to migration file when stored down sql matches it", func(t *testing.T) {
+		migErr := revertError("SELECT 1;\nDROP TABL app.users;\n")
+		assert.Equal(t, "1_users.down.sql", migErr.File)
+		assert.Equal(t, 2, migErr.Line)
+		assert.Equal(t, 6, migErr.Column)
+	})
+
+	t.Run("should point to stored down sql when migration file has changed", func(t *testing.T) {
+		migErr := revertError("-- old\nSELECT 1;\nDROP TABL app.users;\n")
+		assert.Equal(t, "", migErr.File)
+		assert.Equal(t, 3, migErr.Line)
+		assert.Equal(t, "   3 | DROP TABL app.users;\n     |      ^", migErr.Snippet)
+	})
+}
+
+func Test_readRepeatables(t *testing.T) {
+	fsys := fstest.MapFS{
+		"R_views.sql":    {Data: []byte("\\i views/body.sql\nCREATE OR REPLACE VIEW ${schema}.v AS SELECT 1;\n")},
+		"views/body.sql": {Data: []byte("SELECT 1;\n")},
+	}
+
+	checksum := func(vars map[string]string) string {
+		m := &Migrator{fsys: fsys, vars: vars, warn: func(string) {}}
+		source, err := m.source()
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		repeatables, err := m.readRepeatables(source)
+		if !assert.Nil(t, err) || !assert.Len(t, repeatables, 1) {
+			t.FailNow()
+		}
+		assert.NotContains(t, repeatables[0].expanded, "\\i")
+		return repeatables[0].checksum
+	}
+
+	public := checksum(map[string]string{"schema": "public"})
+	assert.NotEqual(t, public, checksum(map[string]string{"schema": "reporting"}))
+
+	fsys["views/body.sql"] = &fstest.MapFile{Data: []byte("SELECT 2;\n")}
+	assert.NotEqual(t, public, checksum(map[string]string{"schema": "public"}))
+}
+
+func Test_New(t *testing.T) {
+	_, err := New(nil)
+	assert.ErrorIs(t, err, pgm.ErrValidation)
+
+	_, err = New(new(pgxpool.Pool), WithPriority("any"))
+	assert.ErrorIs(t, err, pgm.ErrValidation)
+	assert.Equal(t, pgm.EXIT_VALIDATION, pgm.ExitCode(err))
+
+	_, err = New(new(pgxpool.Pool), WithMigrationsTable("public", "migrations;drop"))
+	assert.ErrorIs(t, err, pgm.ErrValidation)
+	assert.EqualError(t, err, "migrations table might contain only letters, numbers and \"_\" symbol")
+}
@@ -0,0 +1,197 @@
+package migrator
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+)
+
+// Option настройка мигратора
+type Option func(m *Migrator)
+
+// WithDir задает директорию с миграциями
+func WithDir(dir string) Option {
+	return func(m *Migrator) {
+		m.dir = dir
+	}
+}
+
+// WithFS задает файловую систему с миграциями (embed.FS, os.DirFS, fstest.MapFS)
+func WithFS(fsys fs.FS) Option {
+	return func(m *Migrator) {
+		m.fsys = fsys
+	}
+}
+
+// WithRecursive включает поиск миграций в поддиректориях
+func WithRecursive(recursive bool) Option {
+	return func(m *Migrator) {
+		m.recursive = recursive
+	}
+}
+
+// WithGoMigrations задает go миграции вместо зарегистрированных через pgm.RegisterGoMigration
+func WithGoMigrations(migrations ...pgm.Migration) Option {
+	return func(m *Migrator) {
+		m.goMigrations = migrations
+	}
+}
+
+// WithPriority задает приоритет файловой системы или базы данных
+func WithPriority(priority pgm.Priority) Option {
+	return func(m *Migrator) {
+		m.priority = priority
+	}
+}
+
+// WithMigrationsTable задает схему и имя таблицы миграций
+func WithMigrationsTable(schema string, table string) Option {
+	return func(m *Migrator) {
+		m.tableSchema = schema
+		m.table = table
+	}
+}
+
+// WithLockTimeout ограничивает время ожидания блокировки таблицы миграций
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithSplitStatements включает выполнение sql миграций по одному запросу.
+// Ошибка указывает на запрос, в котором она произошла, а результат миграции содержит время каждого запроса.
+func WithSplitStatements(split bool) Option {
+	return func(m *Migrator) {
+		m.splitStatements = split
+	}
+}
+
+// WithStatementTimeout ограничивает время выполнения sql миграции или каждого запроса при выполнении по одному запросу
+func WithStatementTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.statementTimeout = timeout
+	}
+}
+
+// WithWarningHandler задает обработчик предупреждений, например о проигнорированных файлах
+func WithWarningHandler(handler func(warning string)) Option {
+	return func(m *Migrator) {
+		m.warn = handler
+	}
+}
+
+// WithPlanHandler задает обработчик шагов, которые Migrate вычислил под блокировкой таблицы миграций.
+// Обработчик вызывается до выполнения шагов и проверок отката.
+func WithPlanHandler(handler func(steps []pgm.PlanStep)) Option {
+	return func(m *Migrator) {
+		m.onPlan = handler
+	}
+}
+
+// WithHook добавляет go колбэк на событие жизненного цикла.
+// Go колбэк выполняется после sql колбэка того же события из директории миграций.
+func WithHook(event pgm.HookEvent, hook pgm.HookFunc) Option {
+	return func(m *Migrator) {
+		m.hooks[event] = append(m.hooks[event], hook)
+	}
+}
+
+// WithVars задает значения плейсхолдеров ${name} в sql миграций и колбэков.
+// Повторный вызов дополняет и переопределяет ранее заданные значения.
+func WithVars(vars map[string]string) Option {
+	return func(m *Migrator) {
+		for k, v := range vars {
+			m.vars[k] = v
+		}
+	}
+}
+
+// WithSearchPath задает search_path транзакции, в которой применяются миграции.
+// Позволяет применять одни и те же миграции с неквалифицированными именами в разные схемы.
+func WithSearchPath(schemas ...string) Option {
+	return func(m *Migrator) {
+		m.searchPath = schemas
+	}
+}
+
+// WithProtected считает базу данных защищенной от отката миграций независимо от отметки в базе данных
+func WithProtected(protected bool) Option {
+	return func(m *Migrator) {
+		m.protected = protected
+	}
+}
+
+// WithAllowRevert разрешает откат миграций защищенной базы данных без подтверждения
+func WithAllowRevert(allow bool) Option {
+	return func(m *Migrator) {
+		m.allowRevert = allow
+	}
+}
+
+// WithMaxReverts ограничивает количество миграций, откатываемых за запуск. 0 снимает ограничение.
+func WithMaxReverts(max int) Option {
+	return func(m *Migrator) {
+		m.maxReverts = max
+	}
+}
+
+// WithMaxLostRows ограничивает примерное количество строк, уничтожаемых откатом миграций за запуск.
+// Если оценка по статистике postgres больше max, откат завершается ошибкой *pgm.DataLossError. 0 снимает ограничение.
+func WithMaxLostRows(max int64) Option {
+	return func(m *Migrator) {
+		m.maxLostRows = max
+	}
+}
+
+// WithForceDataLoss выполняет откат миграций независимо от оценки уничтожаемых данных
+func WithForceDataLoss(force bool) Option {
+	return func(m *Migrator) {
+		m.forceDataLoss = force
+	}
+}
+
+// WithBackupOnRevert копирует таблицы, которые изменит откат миграций, в схему резервных копий перед откатом
+func WithBackupOnRevert(backup bool) Option {
+	return func(m *Migrator) {
+		m.backupOnRevert = backup
+	}
+}
+
+// WithRevertConfirmation задает запрос подтверждения отката миграций перед их откатом в Migrate.
+// Если confirm возвращает false, Migrate завершается ошибкой *pgm.RevertRefusedError.
+func WithRevertConfirmation(confirm func(names []string) bool) Option {
+	return func(m *Migrator) {
+		m.confirmRevert = confirm
+	}
+}
+
+// FromOptions преобразует параметры командной строки в настройки мигратора
+func FromOptions(opts *pgm.MigratorOptions) []Option {
+	options := []Option{
+		WithPriority(opts.Priority),
+		WithMigrationsTable(opts.MigrationsTableSchema, opts.MigrationsTable),
+		WithRecursive(opts.Recursive),
+		WithLockTimeout(opts.LockTimeout),
+		WithVars(opts.Vars),
+		WithSplitStatements(opts.SplitStatements),
+		WithStatementTimeout(opts.StatementTimeout),
+		WithProtected(opts.Protected),
+		WithAllowRevert(opts.AllowRevert),
+		WithMaxReverts(opts.MaxReverts),
+		WithRevertConfirmation(opts.ConfirmRevert),
+		WithMaxLostRows(opts.MaxLostRows),
+		WithForceDataLoss(opts.ForceDataLoss),
+		WithBackupOnRevert(opts.BackupOnRevert),
+		WithPlanHandler(opts.OnPlan),
+	}
+
+	if opts.MigrationsFS != nil {
+		options = append(options, WithFS(opts.MigrationsFS))
+	} else {
+		options = append(options, WithDir(opts.MigrationsDir))
+	}
+
+	return options
+}
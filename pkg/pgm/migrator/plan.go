@@ -0,0 +1,196 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/psql"
+)
+
+// appliedEntry примененная миграция или последовательность примененных миграций,
+// объединенных в базовую миграцию из файловой системы
+type appliedEntry struct {
+	name string
+	rows []pgm.AppliedMigration
+}
+
+// matchSquashed сопоставляет примененные миграции applied со списком миграций, объединенных в базовую миграцию.
+// Возвращает количество сопоставленных миграций или -1, если applied не начинается с объединенных миграций.
+func matchSquashed(squashed []pgm.SquashedMigration, applied []pgm.AppliedMigration) int {
+	consumed := 0
+	for _, s := range squashed {
+		alternatives := s.Alternatives()
+		sort.SliceStable(alternatives, func(i, j int) bool {
+			return len(alternatives[i]) > len(alternatives[j])
+		})
+
+		matched := false
+		for _, alternative := range alternatives {
+			if len(alternative) > len(applied)-consumed {
+				continue
+			}
+
+			matched = true
+			for i, name := range alternative {
+				if applied[consumed+i].Name != name {
+					matched = false
+					break
+				}
+			}
+
+			if matched {
+				consumed += len(alternative)
+				break
+			}
+		}
+
+		if !matched {
+			return -1
+		}
+	}
+
+	return consumed
+}
+
+// partiallySquashed сообщает, являются ли примененные миграции applied началом,
+// но не полной последовательностью миграций, объединенных в базовую миграцию
+func partiallySquashed(squashed []pgm.SquashedMigration, applied []pgm.AppliedMigration) bool {
+	if len(applied) == 0 || len(squashed) == 0 {
+		return false
+	}
+
+	for _, alternative := range squashed[0].Alternatives() {
+		n := min(len(alternative), len(applied))
+
+		matched := true
+		for i := 0; i < n; i++ {
+			if applied[i].Name != alternative[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		if len(applied) < len(alternative) || len(applied) == len(alternative) && len(squashed) > 1 {
+			return true
+		}
+		if len(applied) > len(alternative) && partiallySquashed(squashed[1:], applied[len(alternative):]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collapse группирует примененные миграции. Последовательность миграций, объединенных командой squash
+// в базовую миграцию на той же позиции в файловой системе, считается применением базовой миграции,
+// поэтому базы данных, применившие исходные миграции, не откатываются и не применяют базовую миграцию повторно.
+func collapse(source []pgm.Migration, applied []pgm.AppliedMigration) []appliedEntry {
+	entries := make([]appliedEntry, 0, len(applied))
+
+	for i := 0; i < len(applied); {
+		k := len(entries)
+		if k < len(source) && len(source[k].Squashed) > 0 && applied[i].Name != source[k].Name {
+			if consumed := matchSquashed(source[k].Squashed, applied[i:]); consumed > 0 {
+				entries = append(entries, appliedEntry{name: source[k].Name, rows: applied[i : i+consumed]})
+				i += consumed
+				continue
+			}
+		}
+
+		entries = append(entries, appliedEntry{name: applied[i].Name, rows: applied[i : i+1]})
+		i++
+	}
+
+	return entries
+}
+
+// appliedFrom возвращает примененные миграции сгруппированных записей entries
+func appliedFrom(entries []appliedEntry) []pgm.AppliedMigration {
+	applied := make([]pgm.AppliedMigration, 0, len(entries))
+	for _, e := range entries {
+		applied = append(applied, e.rows...)
+	}
+	return applied
+}
+
+// plan вычисляет шаги синхронизации примененных миграций с миграциями из файловой системы.
+//
+// При приоритете базы данных применяются только недостающие миграции, а расхождение
+// имен на одной позиции считается конфликтом. При приоритете файловой системы
+// все примененные миграции после первого расхождения откатываются в обратном порядке,
+// после чего применяются недостающие миграции.
+// Примененные миграции, объединенные в базовую миграцию, считаются применением базовой миграции.
+func plan(
+	priority pgm.Priority,
+	source []pgm.Migration,
+	applied []pgm.AppliedMigration,
+) ([]pgm.PlanStep, error) {
+	steps := make([]pgm.PlanStep, 0)
+	entries := collapse(source, applied)
+
+	common := 0
+	for common < len(source) && common < len(entries) && source[common].Name == entries[common].name {
+		common++
+	}
+
+	switch priority {
+	case pgm.DB:
+		if common < len(source) && common < len(entries) {
+			if rest := appliedFrom(entries[common:]); partiallySquashed(source[common].Squashed, rest) {
+				names := make([]string, 0, len(rest))
+				for _, a := range rest {
+					names = append(names, a.Name)
+				}
+				return nil, &pgm.PartialSquashError{Baseline: source[common].Name, Applied: names}
+			}
+			return nil, &pgm.ConflictError{Applied: entries[common].name, Source: source[common].Name}
+		}
+	case pgm.FS:
+		for i := len(entries) - 1; i >= common; i-- {
+			for j := len(entries[i].rows) - 1; j >= 0; j-- {
+				steps = append(steps, pgm.PlanStep{Name: entries[i].rows[j].Name, Action: pgm.REVERT})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown priority %s", priority)
+	}
+
+	for _, m := range source[common:] {
+		steps = append(steps, pgm.PlanStep{Name: m.Name, Action: pgm.APPLY})
+	}
+
+	return steps, nil
+}
+
+// repeatable повторяемая миграция с прочитанным sql
+type repeatable struct {
+	migration pgm.Migration
+	sql       string
+	// expanded sql с подключенными файлами psql, lines карта его строк
+	expanded string
+	lines    psql.SourceMap
+	checksum string
+}
+
+// planRepeatables выбирает повторяемые миграции, которые не применялись
+// или контрольная сумма которых изменилась с момента применения
+func planRepeatables(repeatables []repeatable, applied []pgm.AppliedMigration) []pgm.PlanStep {
+	appliedChecksums := make(map[string]string, len(applied))
+	for _, a := range applied {
+		appliedChecksums[a.Name] = a.Checksum
+	}
+
+	steps := make([]pgm.PlanStep, 0)
+	for _, r := range repeatables {
+		if checksum, found := appliedChecksums[r.migration.Name]; found && checksum == r.checksum {
+			continue
+		}
+		steps = append(steps, pgm.PlanStep{Name: r.migration.Name, Action: pgm.APPLY})
+	}
+
+	return steps
+}
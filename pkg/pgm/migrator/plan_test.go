@@ -0,0 +1,240 @@
+package migrator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/stretchr/testify/assert"
+)
+
+func sourceMigrations(names ...string) []pgm.Migration {
+	migrations := make([]pgm.Migration, 0, len(names))
+	for _, name := range names {
+		migrations = append(migrations, pgm.Migration{Name: name, Format: pgm.GO})
+	}
+	return migrations
+}
+
+func appliedMigrations(names ...string) []pgm.AppliedMigration {
+	migrations := make([]pgm.AppliedMigration, 0, len(names))
+	for _, name := range names {
+		migrations = append(migrations, pgm.AppliedMigration{Name: name, Kind: string(pgm.GO)})
+	}
+	return migrations
+}
+
+func Test_plan(t *testing.T) {
+	t.Run("should apply missing migrations with db priority", func(t *testing.T) {
+		steps, err := plan(pgm.DB, sourceMigrations("1_a", "2_b", "3_c"), appliedMigrations("1_a"))
+		assert.Nil(t, err)
+		assert.Equal(t, []pgm.PlanStep{
+			{Name: "2_b", Action: pgm.APPLY},
+			{Name: "3_c", Action: pgm.APPLY},
+		}, steps)
+	})
+
+	t.Run("should return conflict with db priority", func(t *testing.T) {
+		steps, err := plan(pgm.DB, sourceMigrations("1_a", "2_b"), appliedMigrations("1_a", "2_x"))
+		assert.Nil(t, steps)
+		assert.EqualError(t, err, "migrations are not the same in file system and db. 2_x != 2_b")
+		assert.True(t, errors.Is(err, pgm.ErrConflict))
+
+		var conflictErr *pgm.ConflictError
+		if assert.True(t, errors.As(err, &conflictErr)) {
+			assert.Equal(t, "2_x", conflictErr.Applied)
+			assert.Equal(t, "2_b", conflictErr.Source)
+		}
+	})
+
+	t.Run("should ignore extra applied migrations with db priority", func(t *testing.T) {
+		steps, err := plan(pgm.DB, sourceMigrations("1_a"), appliedMigrations("1_a", "2_b"))
+		assert.Nil(t, err)
+		assert.Len(t, steps, 0)
+	})
+
+	t.Run("should revert diverged migrations and apply missing with fs priority", func(t *testing.T) {
+		steps, err := plan(pgm.FS, sourceMigrations("1_a", "4_d"), appliedMigrations("1_a", "2_b", "3_c"))
+		assert.Nil(t, err)
+		assert.Equal(t, []pgm.PlanStep{
+			{Name: "3_c", Action: pgm.REVERT},
+			{Name: "2_b", Action: pgm.REVERT},
+			{Name: "4_d", Action: pgm.APPLY},
+		}, steps)
+	})
+
+	t.Run("should revert all migrations with fs priority when source is empty", func(t *testing.T) {
+		steps, err := plan(pgm.FS, sourceMigrations(), appliedMigrations("1_a", "2_b"))
+		assert.Nil(t, err)
+		assert.Equal(t, []pgm.PlanStep{
+			{Name: "2_b", Action: pgm.REVERT},
+			{Name: "1_a", Action: pgm.REVERT},
+		}, steps)
+	})
+
+	t.Run("should treat applied squashed migrations as applied baseline", func(t *testing.T) {
+		source := sourceMigrations("3_baseline", "4_d")
+		source[0].Squashed = []pgm.SquashedMigration{{Name: "1_a"}, {Name: "2_b"}, {Name: "3_c"}}
+
+		for _, priority := range []pgm.Priority{pgm.DB, pgm.FS} {
+			steps, err := plan(priority, source, appliedMigrations("1_a", "2_b", "3_c"))
+			assert.Nil(t, err)
+			assert.Equal(t, []pgm.PlanStep{{Name: "4_d", Action: pgm.APPLY}}, steps)
+		}
+
+		steps, err := plan(pgm.FS, source, appliedMigrations("3_baseline"))
+		assert.Nil(t, err)
+		assert.Equal(t, []pgm.PlanStep{{Name: "4_d", Action: pgm.APPLY}}, steps)
+	})
+
+	t.Run("should match squashed migrations of a previous baseline", func(t *testing.T) {
+		source := sourceMigrations("5_baseline")
+		source[0].Squashed = []pgm.SquashedMigration{
+			{Name: "3_baseline", Expansions: [][]string{{"1_a", "2_b", "3_c"}}},
+			{Name: "4_d"},
+			{Name: "5_e"},
+		}
+
+		for _, applied := range [][]pgm.AppliedMigration{
+			appliedMigrations("1_a", "2_b", "3_c", "4_d", "5_e"),
+			appliedMigrations("3_baseline", "4_d", "5_e"),
+		} {
+			steps, err := plan(pgm.DB, source, applied)
+			assert.Nil(t, err)
+			assert.Len(t, steps, 0)
+		}
+	})
+
+	t.Run("should not collapse partially applied squashed migrations", func(t *testing.T) {
+		source := sourceMigrations("3_baseline")
+		source[0].Squashed = []pgm.SquashedMigration{{Name: "1_a"}, {Name: "2_b"}, {Name: "3_c"}}
+
+		steps, err := plan(pgm.DB, source, appliedMigrations("1_a", "2_b"))
+		assert.Nil(t, steps)
+		assert.ErrorIs(t, err, pgm.ErrConflict)
+		assert.Equal(t, &pgm.PartialSquashError{Baseline: "3_baseline", Applied: []string{"1_a", "2_b"}}, err)
+		assert.EqualError(
+			t,
+			err,
+			"only part of migrations squashed into 3_baseline is applied to db: 1_a, 2_b. apply the remaining squashed migrations before migrating to the baseline",
+		)
+
+		steps, err = plan(pgm.FS, source, appliedMigrations("1_a", "2_b"))
+		assert.Nil(t, err)
+		assert.Equal(t, []pgm.PlanStep{
+			{Name: "2_b", Action: pgm.REVERT},
+			{Name: "1_a", Action: pgm.REVERT},
+			{Name: "3_baseline", Action: pgm.APPLY},
+		}, steps)
+	})
+
+	t.Run("should detect partially applied squashed migrations of a previous baseline", func(t *testing.T) {
+		source := sourceMigrations("5_baseline")
+		source[0].Squashed = []pgm.SquashedMigration{
+			{Name: "3_baseline", Expansions: [][]string{{"1_a", "2_b", "3_c"}}},
+			{Name: "4_d"},
+			{Name: "5_e"},
+		}
+
+		for _, applied := range [][]pgm.AppliedMigration{
+			appliedMigrations("1_a"),
+			appliedMigrations("1_a", "2_b", "3_c", "4_d"),
+			appliedMigrations("3_baseline"),
+		} {
+			_, err := plan(pgm.DB, source, applied)
+			assert.IsType(t, &pgm.PartialSquashError{}, err)
+		}
+
+		_, err := plan(pgm.DB, source, appliedMigrations("1_a", "4_d"))
+		assert.IsType(t, &pgm.ConflictError{}, err)
+	})
+
+	t.Run("should revert migrations diverged after squashed ones with fs priority", func(t *testing.T) {
+		source := sourceMigrations("2_baseline")
+		source[0].Squashed = []pgm.SquashedMigration{{Name: "1_a"}, {Name: "2_b"}}
+
+		steps, err := plan(pgm.FS, append(source, sourceMigrations("3_c")...), appliedMigrations("1_a", "2_b", "2_x"))
+		assert.Nil(t, err)
+		assert.Equal(t, []pgm.PlanStep{
+			{Name: "2_x", Action: pgm.REVERT},
+			{Name: "3_c", Action: pgm.APPLY},
+		}, steps)
+	})
+
+	t.Run("should return error on unknown priority", func(t *testing.T) {
+		steps, err := plan("unknown", sourceMigrations("1_a"), appliedMigrations())
+		assert.Nil(t, steps)
+		assert.EqualError(t, err, "unknown priority unknown")
+	})
+}
+
+func Test_planRepeatables(t *testing.T) {
+	t.Run("should apply new and changed repeatable migrations", func(t *testing.T) {
+		repeatables := []repeatable{
+			{migration: pgm.Migration{Name: "R_functions"}, checksum: "f1"},
+			{migration: pgm.Migration{Name: "R_triggers"}, checksum: "t1"},
+			{migration: pgm.Migration{Name: "R_views"}, checksum: "v2"},
+		}
+		applied := []pgm.AppliedMigration{
+			{Name: "R_functions", Checksum: "f1"},
+			{Name: "R_views", Checksum: "v1"},
+		}
+
+		steps := planRepeatables(repeatables, applied)
+		assert.Equal(t, []pgm.PlanStep{
+			{Name: "R_triggers", Action: pgm.APPLY},
+			{Name: "R_views", Action: pgm.APPLY},
+		}, steps)
+
+		statuses := repeatableStatus(repeatables, applied)
+		if !assert.Len(t, statuses, 3) {
+			t.FailNow()
+		}
+		assert.Equal(t, pgm.STATE_APPLIED, statuses[0].State)
+		assert.False(t, statuses[0].Modified)
+		assert.Equal(t, pgm.STATE_PENDING, statuses[1].State)
+		assert.Equal(t, pgm.STATE_APPLIED, statuses[2].State)
+		assert.True(t, statuses[2].Modified)
+	})
+}
+
+func Test_status(t *testing.T) {
+	t.Run("should mark applied, pending and missing migrations", func(t *testing.T) {
+		statuses, err := status(sourceMigrations("1_a", "3_c"), appliedMigrations("1_a", "2_b"))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Len(t, statuses, 3) {
+			t.FailNow()
+		}
+		assert.Equal(t, "1_a", statuses[0].Name)
+		assert.Equal(t, pgm.STATE_APPLIED, statuses[0].State)
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Equal(t, "2_b", statuses[1].Name)
+		assert.Equal(t, pgm.STATE_MISSING, statuses[1].State)
+		assert.Equal(t, "3_c", statuses[2].Name)
+		assert.Equal(t, pgm.STATE_PENDING, statuses[2].State)
+		assert.Nil(t, statuses[2].AppliedAt)
+	})
+	t.Run("should report baseline as applied when squashed migrations are applied", func(t *testing.T) {
+		source := sourceMigrations("2_baseline", "3_c")
+		source[0].Squashed = []pgm.SquashedMigration{{Name: "1_a"}, {Name: "2_b"}}
+
+		applied := appliedMigrations("1_a", "2_b")
+		applied[1].AppliedAt = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		statuses, err := status(source, applied)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Len(t, statuses, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, "2_baseline", statuses[0].Name)
+		assert.Equal(t, pgm.STATE_APPLIED, statuses[0].State)
+		assert.Equal(t, applied[1].AppliedAt, *statuses[0].AppliedAt)
+		assert.False(t, statuses[0].Modified)
+		assert.Equal(t, pgm.STATE_PENDING, statuses[1].State)
+	})
+}
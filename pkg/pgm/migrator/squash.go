@@ -0,0 +1,72 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+)
+
+// Replay применяет миграции, предшествующие миграции before, и возвращает полученную схему без таблицы миграций
+// вместе с примененными миграциями. Изменения выполняются в транзакции, которая в конце откатывается.
+// База данных должна быть пустой: объекты, существовавшие до применения миграций, попадут в схему.
+func (m *Migrator) Replay(ctx context.Context, before string) (*catalog.Catalog, []pgm.Migration, error) {
+	source, err := m.source()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idx := -1
+	for i, migration := range source.Migrations {
+		if migration.Name == before {
+			idx = i
+			break
+		}
+	}
+
+	switch {
+	case idx < 0:
+		return nil, nil, fmt.Errorf("migration %s not found", before)
+	case idx == 0:
+		return nil, nil, fmt.Errorf("no migrations before %s", before)
+	}
+
+	migrations := source.Migrations[:idx]
+	for _, migration := range migrations {
+		if migration.Format == pgm.GO {
+			return nil, nil, fmt.Errorf("go migration %s can't be squashed", migration.Name)
+		}
+	}
+
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	applied, err := db.GetMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(applied) > 0 {
+		return nil, nil, fmt.Errorf("database has %d applied migrations, squash requires an empty database", len(applied))
+	}
+
+	for _, migration := range migrations {
+		if _, err = m.apply(ctx, tx, source, migration); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	cat, err := m.snapshot(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cat, migrations, nil
+}
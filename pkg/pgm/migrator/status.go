@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"sort"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	pgmfs "github.com/quadgod/pgm/pkg/pgm/fs"
+)
+
+// status сопоставляет миграции из файловой системы с примененными миграциями.
+// Базовая миграция, объединенные миграции которой применены, считается примененной
+// в момент применения последней из них.
+func status(source []pgm.Migration, applied []pgm.AppliedMigration) ([]pgm.MigrationStatus, error) {
+	entries := collapse(source, applied)
+	applied = make([]pgm.AppliedMigration, 0, len(entries))
+	for _, e := range entries {
+		if len(e.rows) == 1 && e.rows[0].Name == e.name {
+			applied = append(applied, e.rows[0])
+			continue
+		}
+		applied = append(applied, pgm.AppliedMigration{Name: e.name, AppliedAt: e.rows[len(e.rows)-1].AppliedAt})
+	}
+
+	appliedByName := make(map[string]pgm.AppliedMigration, len(applied))
+	for _, a := range applied {
+		appliedByName[a.Name] = a
+	}
+
+	statuses := make([]pgm.MigrationStatus, 0, len(source)+len(applied))
+	seen := make(map[string]struct{}, len(source))
+
+	for _, migration := range source {
+		seen[migration.Name] = struct{}{}
+		st := pgm.MigrationStatus{Name: migration.Name, State: pgm.STATE_PENDING}
+
+		if migration.Format != pgm.GO {
+			upSql, _, err := pgmfs.ReadMigrationSql(migration)
+			if err != nil {
+				return nil, err
+			}
+			st.Checksum = pgm.Checksum(upSql)
+		}
+
+		if a, found := appliedByName[migration.Name]; found {
+			appliedAt := a.AppliedAt
+			st.State = pgm.STATE_APPLIED
+			st.AppliedAt = &appliedAt
+			st.AppliedChecksum = a.Checksum
+			st.Modified = a.Checksum != "" && st.Checksum != "" && a.Checksum != st.Checksum
+		}
+
+		statuses = append(statuses, st)
+	}
+
+	for _, a := range applied {
+		if _, found := seen[a.Name]; found {
+			continue
+		}
+
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, pgm.MigrationStatus{
+			Name:            a.Name,
+			State:           pgm.STATE_MISSING,
+			AppliedAt:       &appliedAt,
+			AppliedChecksum: a.Checksum,
+		})
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses, nil
+}
+
+// repeatableStatus сопоставляет повторяемые миграции с их последним применением
+func repeatableStatus(repeatables []repeatable, applied []pgm.AppliedMigration) []pgm.MigrationStatus {
+	appliedByName := make(map[string]pgm.AppliedMigration, len(applied))
+	for _, a := range applied {
+		appliedByName[a.Name] = a
+	}
+
+	statuses := make([]pgm.MigrationStatus, 0, len(repeatables))
+	for _, r := range repeatables {
+		st := pgm.MigrationStatus{Name: r.migration.Name, State: pgm.STATE_PENDING, Checksum: r.checksum}
+
+		if a, found := appliedByName[r.migration.Name]; found {
+			appliedAt := a.AppliedAt
+			st.State = pgm.STATE_APPLIED
+			st.AppliedAt = &appliedAt
+			st.AppliedChecksum = a.Checksum
+			st.Modified = a.Checksum != r.checksum
+		}
+
+		statuses = append(statuses, st)
+	}
+
+	return statuses
+}
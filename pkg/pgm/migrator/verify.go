@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/db"
+)
+
+// snapshot читает схему базы данных в транзакции миграций без таблицы миграций
+func (m *Migrator) snapshot(ctx context.Context, tx pgx.Tx) (*catalog.Catalog, error) {
+	cat, err := db.ReadCatalogTx(ctx, tx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read schema error: %w", err)
+	}
+
+	return cat.Exclude(m.migTbl(), m.backupsTbl()), nil
+}
+
+// Verify проверяет обратимость миграций, которые применил бы Migrate: каждая миграция применяется,
+// откатывается и применяется снова, а схема после отката сравнивается со схемой до применения.
+// Все изменения выполняются в одной транзакции, которая в конце откатывается.
+// Если откат хотя бы одной миграции не восстановил схему, вместе с результатами возвращается *pgm.IncompleteDownError.
+func (m *Migrator) Verify(ctx context.Context) ([]pgm.MigrationResult, error) {
+	source, err := m.source()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	applied, err := db.GetMigrations(ctx, tx, m.migTbl())
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := plan(m.priority, source.Migrations, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceByName := make(map[string]pgm.Migration, len(source.Migrations))
+	for _, migration := range source.Migrations {
+		sourceByName[migration.Name] = migration
+	}
+
+	results := make([]pgm.MigrationResult, 0, len(steps))
+	incomplete := make([]string, 0)
+
+	var before *catalog.Catalog
+	for _, step := range steps {
+		// миграции, которых нет в директории миграций, только откатываются, как при Migrate
+		if step.Action == pgm.REVERT {
+			result, err := m.revert(ctx, tx, source, step.Name, nil)
+			if err != nil {
+				return results, err
+			}
+
+			results = append(results, *result)
+			before = nil
+			continue
+		}
+
+		if before == nil {
+			if before, err = m.snapshot(ctx, tx); err != nil {
+				return results, err
+			}
+		}
+
+		started := time.Now()
+
+		appliedResult, err := m.apply(ctx, tx, source, sourceByName[step.Name])
+		if err != nil {
+			return results, err
+		}
+
+		if _, err = m.revert(ctx, tx, source, step.Name, nil); err != nil {
+			return results, err
+		}
+
+		reverted, err := m.snapshot(ctx, tx)
+		if err != nil {
+			return results, err
+		}
+		changes := catalog.Diff(before, reverted)
+
+		if _, err = m.apply(ctx, tx, source, sourceByName[step.Name]); err != nil {
+			return results, err
+		}
+
+		if before, err = m.snapshot(ctx, tx); err != nil {
+			return results, err
+		}
+
+		result := pgm.MigrationResult{
+			MigrationName: step.Name,
+			Status:        pgm.VERIFIED,
+			Duration:      time.Since(started),
+			Checksum:      appliedResult.Checksum,
+		}
+		if len(changes) > 0 {
+			result.Status = pgm.INCOMPLETE
+			result.Changes = changes
+			incomplete = append(incomplete, step.Name)
+		}
+
+		results = append(results, result)
+	}
+
+	if len(incomplete) > 0 {
+		return results, &pgm.IncompleteDownError{Names: incomplete}
+	}
+
+	return results, nil
+}
@@ -1,13 +1,44 @@
 package pgm
 
+import (
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+)
+
 type MigrationResultStatus string
 
 const (
 	APPLIED  MigrationResultStatus = "applied"
 	REVERTED MigrationResultStatus = "reverted"
+	// FAILED миграция, при выполнении которой произошла ошибка. Используется в отчетах
+	FAILED MigrationResultStatus = "failed"
+	// VERIFIED откат миграции вернул схему к состоянию до ее применения. Используется командой verify
+	VERIFIED MigrationResultStatus = "verified"
+	// INCOMPLETE после отката миграции схема отличается от схемы до ее применения. Используется командой verify
+	INCOMPLETE MigrationResultStatus = "incomplete"
 )
 
 type MigrationResult struct {
 	MigrationName string                `json:"migrationName"`
 	Status        MigrationResultStatus `json:"status"`
+	Duration      time.Duration         `json:"duration"`
+	// Checksum контрольная сумма up sql примененной миграции, для go миграций пустая
+	Checksum string `json:"checksum,omitempty"`
+	// Statements результаты отдельных запросов при выполнении по одному запросу
+	Statements []StatementResult `json:"statements,omitempty"`
+	// Changes различия схемы после отката миграции со схемой до ее применения для INCOMPLETE
+	Changes []catalog.Change `json:"changes,omitempty"`
+	// DataLoss оценка данных, уничтоженных откатом миграции
+	DataLoss []DataLoss `json:"dataLoss,omitempty"`
+	// Backups резервные копии таблиц, сделанные перед откатом миграции
+	Backups []Backup `json:"backups,omitempty"`
+}
+
+// StatementResult результат выполнения отдельного запроса миграции
+type StatementResult struct {
+	// Line номер строки sql, с которой начинается запрос
+	Line     int           `json:"line"`
+	SQL      string        `json:"sql"`
+	Duration time.Duration `json:"duration"`
 }
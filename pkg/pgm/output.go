@@ -0,0 +1,13 @@
+package pgm
+
+// OutputFormat формат вывода результата команды
+type OutputFormat string
+
+const (
+	// OUTPUT_TEXT построчный вывод для чтения в терминале
+	OUTPUT_TEXT OutputFormat = "text"
+	// OUTPUT_JSON один json документ для разбора инструментами
+	OUTPUT_JSON OutputFormat = "json"
+	// OUTPUT_TABLE таблица для чтения в терминале
+	OUTPUT_TABLE OutputFormat = "table"
+)
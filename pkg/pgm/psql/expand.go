@@ -0,0 +1,359 @@
+// Package psql разворачивает поддерживаемые мета-команды psql в sql файлах миграций:
+// подключение файлов \i и \ir, переменные \set и \unset и загрузку данных \copy ... from.
+package psql
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/quadgod/pgm/pkg/pgm/sqlsplit"
+)
+
+// maxIncludeDepth максимальная вложенность подключаемых файлов
+const maxIncludeDepth = 16
+
+var (
+	metaLineRegexp = regexp.MustCompile(`(?m)^[ \t]*\\`)
+	varNameRegexp  = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	copyRegexp     = regexp.MustCompile(`(?is)^(.+?)\s+(from|to)\s+('(?:[^']|'')*'|\S+)\s*(.*?)\s*;?\s*$`)
+)
+
+// Options параметры разворачивания мета-команд
+type Options struct {
+	// FS файловая система миграций, nil - файлы читаются с диска
+	FS iofs.FS
+	// Root корень директории миграций, от которого \i ищет файлы
+	Root string
+	// Path путь к файлу, из которого прочитан sql. От его директории \ir и \copy ищут файлы
+	Path string
+}
+
+// Line строка файла, из которой получена строка развернутого sql
+type Line struct {
+	File string
+	// Line номер строки в File, начиная с 1. Для исходного sql считается от начала переданного sql
+	Line int
+}
+
+// SourceMap строки файлов, из которых получены строки развернутого sql: элемент i соответствует строке i+1.
+// Строки, которые сгенерировала мета-команда, например COPY из \copy, указывают на строку мета-команды.
+type SourceMap []Line
+
+// Locate возвращает строку файла, из которой получена строка line развернутого sql.
+// Если строки нет в карте, found равен false.
+func (m SourceMap) Locate(line int) (Line, bool) {
+	if line < 1 || line > len(m) {
+		return Line{}, false
+	}
+	return m[line-1], true
+}
+
+// mappedBuilder собирает развернутый sql и карту его строк
+type mappedBuilder struct {
+	out   strings.Builder
+	lines SourceMap
+	// fresh в текущую строку еще ничего не записано, ее источник задаст следующий фрагмент
+	fresh bool
+}
+
+func newMappedBuilder() *mappedBuilder {
+	return &mappedBuilder{lines: SourceMap{{}}, fresh: true}
+}
+
+// add дописывает text, строка k которого получена из строки source(k)
+func (b *mappedBuilder) add(text string, source func(k int) Line) {
+	for k, part := range strings.Split(text, "\n") {
+		if k > 0 {
+			b.out.WriteByte('\n')
+			b.lines = append(b.lines, source(k))
+			b.fresh = true
+		}
+		if part == "" {
+			continue
+		}
+		if b.fresh {
+			b.lines[len(b.lines)-1] = source(k)
+			b.fresh = false
+		}
+		b.out.WriteString(part)
+	}
+}
+
+// addFrom дописывает фрагмент sql[offset:end] файла filePath
+func (b *mappedBuilder) addFrom(sql string, offset int, end int, filePath string) {
+	b.addText(sql[offset:end], filePath, 1+strings.Count(sql[:offset], "\n"))
+}
+
+// addText дописывает text, первая строка которого - строка line файла filePath
+func (b *mappedBuilder) addText(text string, filePath string, line int) {
+	b.add(text, func(k int) Line { return Line{File: filePath, Line: line + k} })
+}
+
+// expander состояние разворачивания мета-команд
+type expander struct {
+	opts Options
+	vars map[string]string
+	// files подключенные файлы от исходного до текущего, для обнаружения циклов
+	files []string
+}
+
+// Expand разворачивает мета-команды psql и возвращает sql, который можно выполнить на сервере:
+//   - \i и \include подставляют файл относительно корня директории миграций;
+//   - \ir и \include_relative подставляют файл относительно текущего файла;
+//   - \set и \unset задают переменные, которые подставляются как :name, :'name' и :"name";
+//   - \copy ... from 'file' и \copy ... from stdin превращаются в COPY ... FROM STDIN с данными.
+//
+// Остальные мета-команды возвращают ошибку. Sql без мета-команд возвращается без изменений.
+//
+// Вместе с sql возвращается карта, по которой строка ошибки в развернутом sql переводится в строку
+// исходного sql или подключенного файла.
+func Expand(sql string, opts Options) (string, SourceMap, error) {
+	e := &expander{opts: opts, vars: make(map[string]string), files: []string{opts.Path}}
+
+	b := newMappedBuilder()
+	if err := e.expand(b, sql, opts.Path); err != nil {
+		return "", nil, err
+	}
+
+	return b.out.String(), b.lines, nil
+}
+
+func (e *expander) expand(b *mappedBuilder, sql string, filePath string) error {
+	if !metaLineRegexp.MatchString(sql) {
+		b.addText(sqlsplit.Interpolate(sql, e.vars), filePath, 1)
+		return nil
+	}
+
+	statements, err := sqlsplit.Split(sql)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	pos := 0
+	for _, st := range statements {
+		b.addFrom(sql, pos, st.Offset, filePath)
+		pos = st.End
+
+		if !st.Meta {
+			b.addText(sqlsplit.Interpolate(st.SQL, e.vars), filePath, 1+strings.Count(sql[:st.Offset], "\n"))
+			b.addFrom(sql, st.Offset+len(st.SQL), st.End, filePath)
+			continue
+		}
+
+		if err := e.meta(b, st, filePath); err != nil {
+			return fmt.Errorf("%s:%d: %w", filePath, st.Line, err)
+		}
+	}
+	b.addFrom(sql, pos, len(sql), filePath)
+
+	return nil
+}
+
+// meta выполняет мета-команду и дописывает sql, которым она заменяется
+func (e *expander) meta(b *mappedBuilder, st sqlsplit.Statement, filePath string) error {
+	command, rest, _ := strings.Cut(st.SQL, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case `\i`, `\include`:
+		return e.include(b, rest, e.opts.Root)
+	case `\ir`, `\include_relative`:
+		return e.include(b, rest, path.Dir(filePath))
+	case `\set`:
+		return e.set(rest)
+	case `\unset`:
+		args, err := splitArgs(rest)
+		if err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return errors.New(`\unset requires a variable name`)
+		}
+		delete(e.vars, args[0])
+		return nil
+	case `\copy`:
+		copySql, err := e.copy(st, rest, path.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		b.add(copySql, func(int) Line { return Line{File: filePath, Line: st.Line} })
+		return nil
+	default:
+		return fmt.Errorf("unsupported psql meta-command %s", command)
+	}
+}
+
+// resolve возвращает путь файла относительно dir
+func resolve(dir string, name string) string {
+	if path.IsAbs(name) {
+		return name
+	}
+	return path.Join(dir, name)
+}
+
+// readFile читает файл из файловой системы миграций или с диска
+func (e *expander) readFile(filePath string) (string, error) {
+	var content []byte
+	var err error
+
+	if e.opts.FS != nil {
+		content, err = iofs.ReadFile(e.opts.FS, filePath)
+	} else {
+		content, err = os.ReadFile(filePath)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// include подставляет содержимое файла с развернутыми мета-командами
+func (e *expander) include(b *mappedBuilder, args string, dir string) error {
+	names, err := splitArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(names) != 1 {
+		return errors.New("include requires a file name")
+	}
+
+	filePath := resolve(dir, names[0])
+	for _, f := range e.files {
+		if f == filePath {
+			return fmt.Errorf("include cycle detected: %s", strings.Join(append(e.files, filePath), " -> "))
+		}
+	}
+
+	if len(e.files) > maxIncludeDepth {
+		return fmt.Errorf("include depth exceeds %d", maxIncludeDepth)
+	}
+
+	content, err := e.readFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	e.files = append(e.files, filePath)
+	defer func() { e.files = e.files[:len(e.files)-1] }()
+
+	return e.expand(b, content, filePath)
+}
+
+// set задает переменную. Значение - объединение остальных аргументов, как в psql
+func (e *expander) set(args string) error {
+	values, err := splitArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return errors.New(`\set requires a variable name`)
+	}
+
+	if !varNameRegexp.MatchString(values[0]) {
+		return fmt.Errorf("invalid variable name %q", values[0])
+	}
+
+	e.vars[values[0]] = strings.Join(values[1:], "")
+
+	return nil
+}
+
+// copy превращает \copy ... from в COPY ... FROM STDIN с данными из файла или из скрипта
+func (e *expander) copy(st sqlsplit.Statement, args string, dir string) (string, error) {
+	match := copyRegexp.FindStringSubmatch(args)
+	if match == nil {
+		return "", errors.New(`invalid \copy command`)
+	}
+
+	target, direction, source, options := match[1], strings.ToLower(match[2]), match[3], match[4]
+	if direction != "from" {
+		return "", errors.New(`\copy ... to is not supported`)
+	}
+
+	var data string
+	inline := false
+	switch strings.ToLower(source) {
+	case "stdin", "pstdin":
+		data = st.CopyData
+		inline = true
+	case "program":
+		return "", errors.New(`\copy ... from program is not supported`)
+	default:
+		names, err := splitArgs(source)
+		if err != nil {
+			return "", err
+		}
+
+		if data, err = e.readFile(resolve(dir, names[0])); err != nil {
+			return "", err
+		}
+
+		if data != "" && !strings.HasSuffix(data, "\n") {
+			data += "\n"
+		}
+	}
+
+	if options != "" {
+		options = " " + options
+	}
+
+	// данные stdin входят в мета-команду вместе с переводом строки после \.
+	copySql := fmt.Sprintf("COPY %s FROM STDIN%s;\n%s\\.", target, options, data)
+	if inline {
+		copySql += "\n"
+	}
+
+	return copySql, nil
+}
+
+// splitArgs разбирает аргументы мета-команды, разделенные пробелами.
+// Аргументы в одинарных кавычках могут содержать пробелы, удвоенная кавычка внутри кавычек означает одну кавычку.
+func splitArgs(s string) ([]string, error) {
+	args := make([]string, 0)
+
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		var arg strings.Builder
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			if s[i] != '\'' {
+				arg.WriteByte(s[i])
+				i++
+				continue
+			}
+
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						arg.WriteByte('\'')
+						i++
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				arg.WriteByte(s[i])
+			}
+
+			if !closed {
+				return nil, errors.New("unterminated quoted argument")
+			}
+		}
+
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,94 @@
+package psql
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Expand(t *testing.T) {
+	fsys := fstest.MapFS{
+		"shared/grants.sql":      {Data: []byte("GRANT SELECT ON :\"table\" TO :role;\n")},
+		"shared/nested.sql":      {Data: []byte("\\ir grants.sql\n")},
+		"shared/cycle.sql":       {Data: []byte("\\ir cycle.sql\n")},
+		"data/countries.csv":     {Data: []byte("de,Germany\nfr,France")},
+		"20250101_countries.sql": {Data: []byte("")},
+	}
+	opts := Options{FS: fsys, Path: "20250101_countries.sql"}
+
+	t.Run("should keep sql without meta-commands", func(t *testing.T) {
+		sql := "CREATE TABLE a (id INT);\nSELECT :x;\n"
+		expanded, _, err := Expand(sql, opts)
+		require.NoError(t, err)
+		assert.Equal(t, sql, expanded)
+	})
+
+	t.Run("should include files and interpolate variables", func(t *testing.T) {
+		sql := "\\set table users\n\\set role 'app_' reader\nCREATE TABLE :table (id INT);\n\\i shared/nested.sql\n\\unset role\nSELECT :'role';\n"
+		expanded, _, err := Expand(sql, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "\n\nCREATE TABLE users (id INT);\nGRANT SELECT ON \"users\" TO app_reader;\n\n\n\nSELECT :'role';\n", expanded)
+	})
+
+	t.Run("should map expanded lines to files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"inc.sql":         {Data: []byte("CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\nSELECT 1;\n")},
+			"1_migration.sql": {Data: []byte("")},
+		}
+		sql := "\\i inc.sql\nSELECT 2;\nSELEC broken;\n"
+
+		expanded, lines, err := Expand(sql, Options{FS: fsys, Root: ".", Path: "1_migration.sql"})
+		require.NoError(t, err)
+		assert.Equal(t, "CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\nSELECT 1;\n\nSELECT 2;\nSELEC broken;\n", expanded)
+
+		line, found := lines.Locate(3)
+		assert.True(t, found)
+		assert.Equal(t, Line{File: "inc.sql", Line: 3}, line)
+
+		line, _ = lines.Locate(7)
+		assert.Equal(t, Line{File: "1_migration.sql", Line: 3}, line)
+
+		_, found = lines.Locate(20)
+		assert.False(t, found)
+	})
+
+	t.Run("should map copy data to copy command", func(t *testing.T) {
+		_, lines, err := Expand("SELECT 1;\n\\copy countries from 'data/countries.csv' with (format csv);\nSELECT 2;", opts)
+		require.NoError(t, err)
+		assert.Equal(t, SourceMap{
+			{File: "20250101_countries.sql", Line: 1},
+			{File: "20250101_countries.sql", Line: 2},
+			{File: "20250101_countries.sql", Line: 2},
+			{File: "20250101_countries.sql", Line: 2},
+			{File: "20250101_countries.sql", Line: 2},
+			{File: "20250101_countries.sql", Line: 3},
+		}, lines)
+	})
+
+	t.Run("should convert copy from file to copy from stdin", func(t *testing.T) {
+		expanded, _, err := Expand("\\copy countries (code, name) from 'data/countries.csv' with (format csv);\nSELECT 1;", opts)
+		require.NoError(t, err)
+		assert.Equal(t, "COPY countries (code, name) FROM STDIN with (format csv);\nde,Germany\nfr,France\n\\.\nSELECT 1;", expanded)
+	})
+
+	t.Run("should convert copy from stdin", func(t *testing.T) {
+		expanded, _, err := Expand("\\copy countries from stdin\nde\tGermany\n\\.\nSELECT 1;", opts)
+		require.NoError(t, err)
+		assert.Equal(t, "COPY countries FROM STDIN;\nde\tGermany\n\\.\nSELECT 1;", expanded)
+	})
+
+	t.Run("should fail on unsupported meta-commands", func(t *testing.T) {
+		_, _, err := Expand("SELECT 1;\n\\gexec\n", opts)
+		assert.EqualError(t, err, "20250101_countries.sql:2: unsupported psql meta-command \\gexec")
+
+		_, _, err = Expand("\\copy countries to 'out.csv'\n", opts)
+		assert.EqualError(t, err, "20250101_countries.sql:1: \\copy ... to is not supported")
+	})
+
+	t.Run("should detect include cycles", func(t *testing.T) {
+		_, _, err := Expand("\\i shared/cycle.sql\n", opts)
+		assert.ErrorContains(t, err, "include cycle detected: 20250101_countries.sql -> shared/cycle.sql -> shared/cycle.sql")
+	})
+}
@@ -0,0 +1,47 @@
+package pgm
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redactedPassword = "xxxxx"
+
+var (
+	keyValuePasswordRegexp = regexp.MustCompile(`((?:^|\s)password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+	urlPasswordRegexp      = regexp.MustCompile(`(postgres(?:ql)?://[^:/?#@\s]*:)[^\s/]*@`)
+	urlQueryPasswordRegexp = regexp.MustCompile("([?&]password=)[^&\\s`'\"]*")
+)
+
+// RedactConnectionString скрывает пароль в строке подключения формата URL или key=value
+func RedactConnectionString(connectionString string) string {
+	if strings.HasPrefix(connectionString, "postgres://") || strings.HasPrefix(connectionString, "postgresql://") {
+		u, err := url.Parse(connectionString)
+		if err != nil {
+			return "<invalid connection string>"
+		}
+
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redactedPassword)
+		}
+
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", redactedPassword)
+			u.RawQuery = query.Encode()
+		}
+
+		return u.String()
+	}
+
+	return keyValuePasswordRegexp.ReplaceAllString(connectionString, "${1}"+redactedPassword)
+}
+
+// RedactSecrets скрывает пароли строк подключения, встречающихся в произвольном тексте,
+// например в сообщениях об ошибках
+func RedactSecrets(text string) string {
+	text = urlPasswordRegexp.ReplaceAllString(text, "${1}"+redactedPassword+"@")
+	text = urlQueryPasswordRegexp.ReplaceAllString(text, "${1}"+redactedPassword)
+	return keyValuePasswordRegexp.ReplaceAllString(text, "${1}"+redactedPassword)
+}
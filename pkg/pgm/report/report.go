@@ -0,0 +1,256 @@
+package report
+
+import (
+	"errors"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/lint"
+)
+
+// SchemaVersion версия структуры json отчета.
+// Увеличивается при несовместимых изменениях, новые поля добавляются без смены версии.
+const SchemaVersion = 1
+
+type Status string
+
+const (
+	SUCCEEDED Status = "succeeded"
+	FAILED    Status = "failed"
+)
+
+// Result результат одной миграции
+type Result struct {
+	Migration  string                    `json:"migration"`
+	Status     pgm.MigrationResultStatus `json:"status"`
+	DurationMs float64                   `json:"durationMs"`
+	Checksum   string                    `json:"checksum,omitempty"`
+	Error      string                    `json:"error,omitempty"`
+	// SQLState, File, Line и Column заполняются для упавшей миграции, если известны
+	SQLState string `json:"sqlState,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	// Statements заполняются при выполнении sql по одному запросу
+	Statements []Statement `json:"statements,omitempty"`
+	// Changes различия схемы после отката миграции, найденные командой verify
+	Changes []catalog.Change `json:"changes,omitempty"`
+	// DataLoss оценка данных, уничтоженных откатом миграции
+	DataLoss []pgm.DataLoss `json:"dataLoss,omitempty"`
+	// Backups резервные копии таблиц, сделанные перед откатом миграции
+	Backups []pgm.Backup `json:"backups,omitempty"`
+}
+
+// Statement результат отдельного запроса миграции
+type Statement struct {
+	Line       int     `json:"line"`
+	SQL        string  `json:"sql"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Target результат миграции тенанта или базы данных
+type Target struct {
+	Target     string           `json:"target"`
+	Status     pgm.TargetStatus `json:"status"`
+	DurationMs float64          `json:"durationMs"`
+	Results    []Result         `json:"results"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// Totals итоги выполнения команды
+type Totals struct {
+	Applied  int `json:"applied"`
+	Reverted int `json:"reverted"`
+	Failed   int `json:"failed"`
+	// Verified и Incomplete заполняются командой verify
+	Verified   int `json:"verified,omitempty"`
+	Incomplete int `json:"incomplete,omitempty"`
+	// Targets, FailedTargets и SkippedTargets заполняются при миграции нескольких тенантов или баз данных
+	Targets        int `json:"targets,omitempty"`
+	FailedTargets  int `json:"failedTargets,omitempty"`
+	SkippedTargets int `json:"skippedTargets,omitempty"`
+}
+
+// Report отчет о выполнении команды
+type Report struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Command       pgm.Command    `json:"command"`
+	Status        Status         `json:"status"`
+	StartedAt     time.Time      `json:"startedAt"`
+	DurationMs    float64        `json:"durationMs"`
+	Plan          []pgm.PlanStep `json:"plan,omitempty"`
+	Results       []Result       `json:"results"`
+	Targets       []Target       `json:"targets,omitempty"`
+	// Files созданные командой файлы: миграции команды create или снимок схемы команды dump
+	Files []string `json:"files,omitempty"`
+	// Diff различия схем, найденные командой diff
+	Diff []catalog.Change `json:"diff,omitempty"`
+	// Findings нарушения правил, найденные командой lint
+	Findings []lint.Finding `json:"findings,omitempty"`
+	// Backups резервные копии таблиц, найденные командой backups или удаленные командой purge
+	Backups []pgm.Backup `json:"backups,omitempty"`
+	Totals  Totals       `json:"totals"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// New создает отчет о выполнении команды, начатой в момент вызова
+func New(command pgm.Command) *Report {
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		Command:       command,
+		Status:        SUCCEEDED,
+		StartedAt:     time.Now(),
+		Results:       make([]Result, 0),
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func toResults(results []pgm.MigrationResult) []Result {
+	converted := make([]Result, 0, len(results))
+	for _, r := range results {
+		converted = append(converted, Result{
+			Migration:  r.MigrationName,
+			Status:     r.Status,
+			DurationMs: durationMs(r.Duration),
+			Checksum:   r.Checksum,
+			Statements: toStatements(r.Statements),
+			Changes:    r.Changes,
+			DataLoss:   r.DataLoss,
+			Backups:    r.Backups,
+		})
+	}
+	return converted
+}
+
+func toStatements(statements []pgm.StatementResult) []Statement {
+	if len(statements) == 0 {
+		return nil
+	}
+
+	converted := make([]Statement, 0, len(statements))
+	for _, s := range statements {
+		converted = append(converted, Statement{Line: s.Line, SQL: s.SQL, DurationMs: durationMs(s.Duration)})
+	}
+	return converted
+}
+
+// failedResult возвращает результат упавшей миграции, если ошибка относится к миграции
+func failedResult(err error) (Result, bool) {
+	var migErr *pgm.MigrationError
+	if !errors.As(err, &migErr) {
+		return Result{}, false
+	}
+
+	return Result{
+		Migration: migErr.Name,
+		Status:    pgm.FAILED,
+		Error:     pgm.RedactSecrets(err.Error()),
+		SQLState:  migErr.SQLState,
+		File:      migErr.File,
+		Line:      migErr.Line,
+		Column:    migErr.Column,
+	}, true
+}
+
+// SetPlan сохраняет план, по которому выполнялась команда
+func (r *Report) SetPlan(steps []pgm.PlanStep) {
+	r.Plan = steps
+}
+
+// AddResults добавляет результаты примененных или откаченных миграций
+func (r *Report) AddResults(results ...pgm.MigrationResult) {
+	r.Results = append(r.Results, toResults(results)...)
+}
+
+// AddTargets добавляет результаты миграции тенантов или баз данных
+func (r *Report) AddTargets(targets []pgm.TargetResult) {
+	for _, t := range targets {
+		target := Target{
+			Target:     t.Target,
+			Status:     t.Status,
+			DurationMs: durationMs(t.Duration),
+			Results:    toResults(t.Results),
+		}
+
+		if t.Err != nil {
+			target.Error = pgm.RedactSecrets(t.Err.Error())
+			if failed, ok := failedResult(t.Err); ok {
+				target.Results = append(target.Results, failed)
+			}
+		}
+
+		r.Targets = append(r.Targets, target)
+	}
+}
+
+// AddFiles добавляет созданные файлы
+func (r *Report) AddFiles(files ...string) {
+	r.Files = append(r.Files, files...)
+}
+
+// AddDiff добавляет различия схем
+func (r *Report) AddDiff(changes []catalog.Change) {
+	r.Diff = append(r.Diff, changes...)
+}
+
+// AddFindings добавляет нарушения правил проверки миграций
+func (r *Report) AddFindings(findings []lint.Finding) {
+	r.Findings = append(r.Findings, findings...)
+}
+
+// AddBackups добавляет резервные копии таблиц
+func (r *Report) AddBackups(backups []pgm.Backup) {
+	r.Backups = append(r.Backups, backups...)
+}
+
+// Fail отмечает команду как завершившуюся ошибкой. Пароли строк подключения в тексте ошибки скрываются.
+func (r *Report) Fail(err error) {
+	r.Status = FAILED
+	r.Error = pgm.RedactSecrets(err.Error())
+
+	if failed, ok := failedResult(err); ok {
+		r.Results = append(r.Results, failed)
+	}
+}
+
+// Finish подсчитывает итоги и длительность команды
+func (r *Report) Finish() {
+	r.DurationMs = durationMs(time.Since(r.StartedAt))
+	r.Totals = Totals{}
+
+	count := func(results []Result) {
+		for _, res := range results {
+			switch res.Status {
+			case pgm.APPLIED:
+				r.Totals.Applied++
+			case pgm.REVERTED:
+				r.Totals.Reverted++
+			case pgm.FAILED:
+				r.Totals.Failed++
+			case pgm.VERIFIED:
+				r.Totals.Verified++
+			case pgm.INCOMPLETE:
+				r.Totals.Incomplete++
+			}
+		}
+	}
+
+	count(r.Results)
+
+	for _, t := range r.Targets {
+		count(t.Results)
+		r.Totals.Targets++
+		switch t.Status {
+		case pgm.TARGET_FAILED:
+			r.Totals.FailedTargets++
+			r.Status = FAILED
+		case pgm.TARGET_SKIPPED:
+			r.Totals.SkippedTargets++
+			r.Status = FAILED
+		}
+	}
+}
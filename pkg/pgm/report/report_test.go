@@ -0,0 +1,227 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Report(t *testing.T) {
+	t.Run("should write failed migrate report as json document", func(t *testing.T) {
+		r := New(pgm.MIGRATE)
+		r.SetPlan([]pgm.PlanStep{{Name: "1_a", Action: pgm.APPLY}, {Name: "2_b", Action: pgm.APPLY}})
+		r.AddResults(pgm.MigrationResult{MigrationName: "1_a", Status: pgm.APPLIED, Duration: 1500 * time.Microsecond, Checksum: "abc"})
+		r.Fail(&pgm.MigrationError{Name: "2_b", Action: pgm.APPLY, Err: errors.New("syntax error")})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		if !assert.Nil(t, Write(buf, pgm.OUTPUT_JSON, r)) {
+			t.FailNow()
+		}
+
+		var doc map[string]any
+		if !assert.Nil(t, json.Unmarshal(buf.Bytes(), &doc)) {
+			t.FailNow()
+		}
+		assert.Equal(t, float64(SchemaVersion), doc["schemaVersion"])
+		assert.Equal(t, "migrate", doc["command"])
+		assert.Equal(t, "failed", doc["status"])
+		assert.Equal(t, "apply 2_b migration error: syntax error", doc["error"])
+		assert.Len(t, doc["plan"], 2)
+		assert.Equal(t, []any{
+			map[string]any{"migration": "1_a", "status": "applied", "durationMs": 1.5, "checksum": "abc"},
+			map[string]any{"migration": "2_b", "status": "failed", "durationMs": float64(0), "error": "apply 2_b migration error: syntax error"},
+		}, doc["results"])
+		assert.Equal(t, map[string]any{"applied": float64(1), "reverted": float64(0), "failed": float64(1)}, doc["totals"])
+	})
+
+	t.Run("should count targets", func(t *testing.T) {
+		r := New(pgm.MIGRATE)
+		r.AddTargets([]pgm.TargetResult{
+			{Target: "tenant_a", Status: pgm.TARGET_SUCCEEDED, Results: []pgm.MigrationResult{{MigrationName: "1_a", Status: pgm.APPLIED}}},
+			{Target: "tenant_b", Status: pgm.TARGET_FAILED, Err: &pgm.MigrationError{Name: "1_a", Action: pgm.APPLY, Err: errors.New("boom")}},
+			{Target: "tenant_c", Status: pgm.TARGET_SKIPPED},
+		})
+		r.Finish()
+
+		assert.Equal(t, FAILED, r.Status)
+		assert.Equal(t, Totals{Applied: 1, Failed: 1, Targets: 3, FailedTargets: 1, SkippedTargets: 1}, r.Totals)
+		assert.Equal(t, pgm.FAILED, r.Targets[1].Results[0].Status)
+	})
+
+	t.Run("should write text report", func(t *testing.T) {
+		r := New(pgm.DOWN)
+		r.AddResults(pgm.MigrationResult{MigrationName: "2_b", Status: pgm.REVERTED, Duration: 2 * time.Millisecond})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "reverted 2_b (2.0ms)\ndown succeeded: 0 applied, 1 reverted, 0 failed\n", buf.String())
+	})
+
+	t.Run("should write data loss of text report", func(t *testing.T) {
+		r := New(pgm.DOWN)
+		r.AddResults(pgm.MigrationResult{
+			MigrationName: "2_b",
+			Status:        pgm.REVERTED,
+			Duration:      2 * time.Millisecond,
+			DataLoss: []pgm.DataLoss{
+				{Migration: "2_b", Table: "public.orders", Line: 2, Rows: 1200, Bytes: 2 * 1024 * 1024},
+				{Migration: "2_b", Table: "public.users", Column: "note", Line: 1, Rows: 10, Bytes: 80},
+			},
+		})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "reverted 2_b (2.0ms)\n"+
+			"  data loss: table public.orders ~1200 rows, 2.0 MB\n"+
+			"  data loss: column public.users.note ~10 rows, 80 B\n"+
+			"down succeeded: 0 applied, 1 reverted, 0 failed\n", buf.String())
+	})
+
+	t.Run("should write backups of text report", func(t *testing.T) {
+		createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		backup := pgm.Backup{
+			Schema:    "pgm_backup_20261019_120000",
+			Table:     "public__orders",
+			Source:    "public.orders",
+			Migration: "2_b",
+			Rows:      1200,
+			CreatedAt: createdAt,
+		}
+
+		r := New(pgm.MIGRATE)
+		r.AddResults(pgm.MigrationResult{MigrationName: "2_b", Status: pgm.REVERTED, Duration: time.Millisecond, Backups: []pgm.Backup{backup}})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "reverted 2_b (1.0ms)\n"+
+			"  backup: public.orders -> pgm_backup_20261019_120000.public__orders (1200 rows)\n"+
+			"migrate succeeded: 0 applied, 1 reverted, 0 failed\n", buf.String())
+
+		r = New(pgm.PURGE)
+		r.AddBackups([]pgm.Backup{backup})
+		r.Finish()
+
+		buf.Reset()
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "2026-10-19T12:00:00Z public.orders -> pgm_backup_20261019_120000.public__orders (1200 rows, 2_b)\n"+
+			"purge succeeded: 1 backups purged\n", buf.String())
+	})
+
+	t.Run("should write statements of text report", func(t *testing.T) {
+		r := New(pgm.MIGRATE)
+		r.AddResults(pgm.MigrationResult{
+			MigrationName: "1_a",
+			Status:        pgm.APPLIED,
+			Duration:      3 * time.Millisecond,
+			Statements: []pgm.StatementResult{
+				{Line: 1, SQL: "CREATE TABLE a (id INT)", Duration: time.Millisecond},
+				{Line: 3, SQL: "CREATE INDEX ON a (id)", Duration: 2 * time.Millisecond},
+			},
+		})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "applied 1_a (3.0ms)\n  line 1 (1.0ms)\n  line 3 (2.0ms)\n"+
+			"migrate succeeded: 1 applied, 0 reverted, 0 failed\n", buf.String())
+		assert.Equal(t, Statement{Line: 3, SQL: "CREATE INDEX ON a (id)", DurationMs: 2}, r.Results[0].Statements[1])
+	})
+
+	t.Run("should write text diff report", func(t *testing.T) {
+		r := New(pgm.DIFF)
+		r.AddDiff([]catalog.Change{
+			{Type: catalog.ADDED, Kind: catalog.TABLE, Name: "public.audit"},
+			{
+				Type:       catalog.CHANGED,
+				Kind:       catalog.COLUMN,
+				Parent:     "public.users",
+				Name:       "email",
+				Attributes: []catalog.AttributeChange{{Name: "type", Source: "text", Target: "character varying(255)"}},
+			},
+			{
+				Type:   catalog.CHANGED,
+				Kind:   catalog.INDEX,
+				Parent: "public.users",
+				Name:   "users_email",
+				Source: "CREATE INDEX users_email ON public.users USING btree (email)",
+				Target: "CREATE UNIQUE INDEX users_email ON public.users USING btree (email)",
+			},
+		})
+		r.Fail(&pgm.SchemaDiffError{Differences: 3})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "+ table public.audit\n"+
+			"~ column public.users email\n"+
+			"    type: text -> character varying(255)\n"+
+			"~ index public.users users_email\n"+
+			"    - CREATE INDEX users_email ON public.users USING btree (email)\n"+
+			"    + CREATE UNIQUE INDEX users_email ON public.users USING btree (email)\n"+
+			"diff failed: 3 differences\n"+
+			"error: schemas differ: 3 differences\n", buf.String())
+	})
+
+	t.Run("should write text verify report", func(t *testing.T) {
+		r := New(pgm.VERIFY)
+		r.AddResults(
+			pgm.MigrationResult{MigrationName: "1_a", Status: pgm.VERIFIED, Duration: time.Millisecond},
+			pgm.MigrationResult{
+				MigrationName: "2_b",
+				Status:        pgm.INCOMPLETE,
+				Duration:      2 * time.Millisecond,
+				Changes:       []catalog.Change{{Type: catalog.ADDED, Kind: catalog.SEQUENCE, Name: "public.b_seq"}},
+			},
+		)
+		r.Fail(&pgm.IncompleteDownError{Names: []string{"2_b"}})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "verified 1_a (1.0ms)\n"+
+			"incomplete 2_b (2.0ms)\n"+
+			"  + sequence public.b_seq\n"+
+			"verify failed: 1 verified, 1 incomplete, 0 reverted, 0 failed\n"+
+			"error: down doesn't restore the schema for migrations: 2_b\n", buf.String())
+	})
+
+	t.Run("should write text lint report", func(t *testing.T) {
+		r := New(pgm.LINT)
+		r.AddFindings([]lint.Finding{
+			{Rule: "add-column-not-null", Severity: lint.ERROR, Migration: "1_a", File: "migrations/1_a.up.sql", Line: 3, Message: "column status is added to orders as NOT NULL without a default"},
+			{Rule: "rename", Severity: lint.WARNING, Migration: "2_b", File: "migrations/2_b.sql", Line: 2, Message: "table users is renamed to customers"},
+		})
+		r.Fail(&pgm.LintError{Errors: 1, Warnings: 1})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TEXT, r))
+		assert.Equal(t, "error migrations/1_a.up.sql:3 add-column-not-null: column status is added to orders as NOT NULL without a default\n"+
+			"warning migrations/2_b.sql:2 rename: table users is renamed to customers\n"+
+			"lint failed: 1 errors, 1 warnings\n"+
+			"error: lint found 1 errors and 1 warnings\n", buf.String())
+	})
+
+	t.Run("should write table report", func(t *testing.T) {
+		r := New(pgm.MIGRATE)
+		r.AddResults(pgm.MigrationResult{MigrationName: "1_initial", Status: pgm.APPLIED, Duration: time.Millisecond, Checksum: "0123456789abcdef"})
+		r.Finish()
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, Write(buf, pgm.OUTPUT_TABLE, r))
+		assert.Equal(t, "MIGRATION  STATUS   DURATION  CHECKSUM\n"+
+			"1_initial  applied  1.0ms     0123456789ab\n"+
+			"\nmigrate succeeded: 1 applied, 0 reverted, 0 failed\n", buf.String())
+	})
+}
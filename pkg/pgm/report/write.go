@@ -0,0 +1,244 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quadgod/pgm/pkg/pgm"
+	"github.com/quadgod/pgm/pkg/pgm/catalog"
+	"github.com/quadgod/pgm/pkg/pgm/lint"
+)
+
+// Write выводит отчет в заданном формате
+func Write(w io.Writer, format pgm.OutputFormat, r *Report) error {
+	switch format {
+	case pgm.OUTPUT_JSON:
+		return writeJSON(w, r)
+	case pgm.OUTPUT_TABLE:
+		return writeTable(w, r)
+	case pgm.OUTPUT_TEXT, "":
+		return writeText(w, r)
+	default:
+		return fmt.Errorf("unknown output format %s", format)
+	}
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func writeText(w io.Writer, r *Report) error {
+	ew := &errWriter{w: w}
+
+	for _, f := range r.Files {
+		ew.printf("created %s\n", f)
+	}
+
+	writeResultsText(ew, "", r.Results)
+
+	for _, t := range r.Targets {
+		ew.printf("%s %s (%.1fms)\n", t.Target, t.Status, t.DurationMs)
+		writeResultsText(ew, "  ", t.Results)
+		if t.Error != "" {
+			ew.printf("  error: %s\n", t.Error)
+		}
+	}
+
+	writeDiffText(ew, "", r.Diff)
+
+	for _, f := range r.Findings {
+		ew.printf("%s %s:%d %s: %s\n", f.Severity, f.File, f.Line, f.Rule, f.Message)
+	}
+
+	for _, b := range r.Backups {
+		ew.printf("%s %s -> %s (%d rows, %s)\n", b.CreatedAt.Format(time.RFC3339), b.Source, backupTable(b), b.Rows, b.Migration)
+	}
+
+	if summary := summaryText(r); summary != "" {
+		ew.printf("%s\n", summary)
+	}
+
+	if r.Error != "" {
+		ew.printf("error: %s\n", r.Error)
+	}
+
+	return ew.err
+}
+
+// backupTable возвращает имя резервной копии таблицы со схемой
+func backupTable(b pgm.Backup) string {
+	return b.Schema + "." + b.Table
+}
+
+// changeSymbols обозначения различий схем в текстовом отчете
+var changeSymbols = map[catalog.ChangeType]string{
+	catalog.ADDED:   "+",
+	catalog.REMOVED: "-",
+	catalog.CHANGED: "~",
+}
+
+// changeObject возвращает тип и имя объекта различия схем
+func changeObject(c catalog.Change) string {
+	if c.Parent == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s %s", c.Kind, c.Parent, c.Name)
+}
+
+func writeDiffText(ew *errWriter, indent string, changes []catalog.Change) {
+	for _, c := range changes {
+		ew.printf("%s%s %s\n", indent, changeSymbols[c.Type], changeObject(c))
+		if c.Type != catalog.CHANGED {
+			continue
+		}
+
+		if len(c.Attributes) > 0 {
+			for _, a := range c.Attributes {
+				ew.printf("%s    %s: %s -> %s\n", indent, a.Name, a.Source, a.Target)
+			}
+			continue
+		}
+
+		for _, line := range strings.Split(c.Source, "\n") {
+			ew.printf("%s    - %s\n", indent, line)
+		}
+		for _, line := range strings.Split(c.Target, "\n") {
+			ew.printf("%s    + %s\n", indent, line)
+		}
+	}
+}
+
+// summaryText возвращает итоговую строку отчета. Для create итоговая строка не выводится.
+func summaryText(r *Report) string {
+	switch r.Command {
+	case pgm.CREATE:
+		return ""
+	case pgm.MIGRATE, pgm.DOWN:
+		return fmt.Sprintf("%s %s: %s", r.Command, r.Status, totalsText(r.Totals))
+	case pgm.DIFF:
+		return fmt.Sprintf("%s %s: %d differences", r.Command, r.Status, len(r.Diff))
+	case pgm.LINT:
+		errors, warnings := lint.Count(r.Findings)
+		return fmt.Sprintf("%s %s: %d errors, %d warnings", r.Command, r.Status, errors, warnings)
+	case pgm.BACKUPS:
+		return fmt.Sprintf("%s %s: %d backups", r.Command, r.Status, len(r.Backups))
+	case pgm.PURGE:
+		return fmt.Sprintf("%s %s: %d backups purged", r.Command, r.Status, len(r.Backups))
+	case pgm.VERIFY:
+		return fmt.Sprintf("%s %s: %d verified, %d incomplete, %d reverted, %d failed",
+			r.Command, r.Status, r.Totals.Verified, r.Totals.Incomplete, r.Totals.Reverted, r.Totals.Failed)
+	default:
+		return fmt.Sprintf("%s %s", r.Command, r.Status)
+	}
+}
+
+func writeResultsText(ew *errWriter, indent string, results []Result) {
+	for _, res := range results {
+		if res.Status == pgm.FAILED {
+			ew.printf("%s%s %s\n", indent, res.Status, res.Migration)
+			continue
+		}
+		ew.printf("%s%s %s (%.1fms)\n", indent, res.Status, res.Migration, res.DurationMs)
+		for _, st := range res.Statements {
+			ew.printf("%s  line %d (%.1fms)\n", indent, st.Line, st.DurationMs)
+		}
+		for _, l := range res.DataLoss {
+			ew.printf("%s  data loss: %s ~%d rows, %s\n", indent, l.Object(), l.Rows, pgm.FormatBytes(l.Bytes))
+		}
+		for _, b := range res.Backups {
+			ew.printf("%s  backup: %s -> %s (%d rows)\n", indent, b.Source, backupTable(b), b.Rows)
+		}
+		writeDiffText(ew, indent+"  ", res.Changes)
+	}
+}
+
+func totalsText(t Totals) string {
+	text := fmt.Sprintf("%d applied, %d reverted, %d failed", t.Applied, t.Reverted, t.Failed)
+	if t.Targets > 0 {
+		text += fmt.Sprintf(", %d targets (%d failed, %d skipped)", t.Targets, t.FailedTargets, t.SkippedTargets)
+	}
+	return text
+}
+
+func writeTable(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	ew := &errWriter{w: tw}
+
+	if len(r.Files) > 0 {
+		ew.printf("FILE\n")
+		for _, f := range r.Files {
+			ew.printf("%s\n", f)
+		}
+	}
+
+	if len(r.Targets) > 0 {
+		ew.printf("TARGET\tSTATUS\tMIGRATION\tRESULT\tDURATION\n")
+		for _, t := range r.Targets {
+			ew.printf("%s\t%s\t\t\t%.1fms\n", t.Target, t.Status, t.DurationMs)
+			for _, res := range t.Results {
+				ew.printf("\t\t%s\t%s\t%.1fms\n", res.Migration, res.Status, res.DurationMs)
+			}
+		}
+	} else if r.Command == pgm.DIFF {
+		ew.printf("CHANGE\tOBJECT\n")
+		for _, c := range r.Diff {
+			ew.printf("%s\t%s\n", c.Type, changeObject(c))
+		}
+	} else if r.Command == pgm.LINT {
+		ew.printf("SEVERITY\tFILE\tLINE\tRULE\tMESSAGE\n")
+		for _, f := range r.Findings {
+			ew.printf("%s\t%s\t%d\t%s\t%s\n", f.Severity, f.File, f.Line, f.Rule, f.Message)
+		}
+	} else if r.Command == pgm.BACKUPS || r.Command == pgm.PURGE {
+		ew.printf("CREATED\tSOURCE\tBACKUP\tROWS\tMIGRATION\n")
+		for _, b := range r.Backups {
+			ew.printf("%s\t%s\t%s\t%d\t%s\n", b.CreatedAt.Format(time.RFC3339), b.Source, backupTable(b), b.Rows, b.Migration)
+		}
+	} else if r.Command == pgm.MIGRATE || r.Command == pgm.DOWN || r.Command == pgm.VERIFY {
+		ew.printf("MIGRATION\tSTATUS\tDURATION\tCHECKSUM\n")
+		for _, res := range r.Results {
+			ew.printf("%s\t%s\t%.1fms\t%s\n", res.Migration, res.Status, res.DurationMs, shortChecksum(res.Checksum))
+		}
+	}
+
+	if ew.err == nil {
+		ew.err = tw.Flush()
+	}
+
+	ew.w = w
+	if summary := summaryText(r); summary != "" {
+		ew.printf("\n%s\n", summary)
+	}
+
+	if r.Error != "" {
+		ew.printf("error: %s\n", r.Error)
+	}
+
+	return ew.err
+}
+
+func shortChecksum(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
+
+// errWriter запоминает первую ошибку записи, чтобы не проверять каждый вызов
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
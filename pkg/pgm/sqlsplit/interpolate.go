@@ -0,0 +1,117 @@
+package sqlsplit
+
+import (
+	"strings"
+)
+
+// isVarChar символ имени переменной psql
+func isVarChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// quotedVar разбирает ссылку :'name' или :"name", начинающуюся с двоеточия в i,
+// и возвращает имя переменной и смещение после ссылки
+func quotedVar(sql string, i int) (string, int, bool) {
+	quote := sql[i+1]
+	j := i + 2
+	for j < len(sql) && isVarChar(sql[j]) {
+		j++
+	}
+
+	if j == i+2 || j >= len(sql) || sql[j] != quote {
+		return "", 0, false
+	}
+
+	return sql[i+2 : j], j + 1, true
+}
+
+// Interpolate подставляет переменные psql в запрос вне строк, идентификаторов в кавычках и комментариев:
+// :name - значение как есть, :'name' - строковый литерал, :"name" - идентификатор в кавычках.
+// Ссылки на неизвестные переменные и приведения типов :: остаются без изменений.
+func Interpolate(sql string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(sql, ":") {
+		return sql
+	}
+
+	var out strings.Builder
+	from := 0
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end, err := skipBlockComment(sql, i)
+			if err != nil {
+				i = len(sql)
+				continue
+			}
+			i = end
+		case c == '\'' || c == '"':
+			escapes := c == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i < 2 || !isIdentChar(sql[i-2]))
+			end, err := skipQuoted(sql, i, c, escapes)
+			if err != nil {
+				i = len(sql)
+				continue
+			}
+			i = end
+		case c == '$':
+			tag := dollarTag(sql, i)
+			if tag == "" {
+				i++
+				continue
+			}
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				i = len(sql)
+				continue
+			}
+			i += len(tag) + end + len(tag)
+		case c == ':' && i+1 < len(sql) && sql[i+1] == ':':
+			i += 2
+		case c == ':' && i+1 < len(sql) && (sql[i+1] == '\'' || sql[i+1] == '"'):
+			name, end, ok := quotedVar(sql, i)
+			value, found := vars[name]
+			if !ok || !found {
+				i++
+				continue
+			}
+
+			out.WriteString(sql[from:i])
+			if sql[i+1] == '\'' {
+				out.WriteString("'" + strings.ReplaceAll(value, "'", "''") + "'")
+			} else {
+				out.WriteString(`"` + strings.ReplaceAll(value, `"`, `""`) + `"`)
+			}
+			from, i = end, end
+		case c == ':' && i+1 < len(sql) && isVarChar(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isVarChar(sql[end]) {
+				end++
+			}
+
+			value, found := vars[sql[i+1:end]]
+			if !found {
+				i = end
+				continue
+			}
+
+			out.WriteString(sql[from:i])
+			out.WriteString(value)
+			from, i = end, end
+		default:
+			i++
+		}
+	}
+
+	out.WriteString(sql[from:])
+
+	return out.String()
+}
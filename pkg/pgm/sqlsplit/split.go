@@ -0,0 +1,399 @@
+// Package sqlsplit разбивает sql скрипт на отдельные запросы с учетом синтаксиса postgres:
+// строк в одинарных кавычках и строк с экранированием E'...', идентификаторов в двойных кавычках,
+// dollar-quoted тел функций, вложенных блочных комментариев, тел BEGIN ATOMIC и данных COPY ... FROM STDIN.
+// Строки, начинающиеся с обратной косой черты вне запроса, возвращаются как мета-команды psql.
+package sqlsplit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	copyFromStdinRegexp     = regexp.MustCompile(`(?is)^COPY\b.*\bFROM\s+STDIN\b`)
+	metaCopyFromStdinRegexp = regexp.MustCompile(`(?is)^\\copy\b.*\bfrom\s+p?stdin\b`)
+	routineRegexp           = regexp.MustCompile(`(?is)^CREATE\s+(OR\s+REPLACE\s+)?(FUNCTION|PROCEDURE)\b`)
+)
+
+// Statement запрос sql скрипта
+type Statement struct {
+	// SQL текст запроса без завершающей точки с запятой
+	SQL string
+	// Line номер строки скрипта, с которой начинается запрос, начиная с 1
+	Line int
+	// CopyData данные COPY ... FROM STDIN или \copy ... FROM STDIN без завершающей строки \.
+	CopyData string
+	// Meta строка является мета-командой psql, SQL содержит строку целиком, например \i shared.sql
+	Meta bool
+	// Offset смещение SQL в скрипте в байтах
+	Offset int
+	// End смещение конца запроса в скрипте: после точки с запятой, данных COPY или строки мета-команды
+	End int
+}
+
+// IsCopyFromStdin запрос загружает данные COPY ... FROM STDIN
+func (s Statement) IsCopyFromStdin() bool {
+	if s.Meta {
+		return false
+	}
+	return copyFromStdinRegexp.MatchString(StripLeadingComments(s.SQL))
+}
+
+// hasCopyData запрос или мета-команда \copy сопровождаются данными до строки \.
+func (s Statement) hasCopyData() bool {
+	if s.Meta {
+		return metaCopyFromStdinRegexp.MatchString(s.SQL)
+	}
+	return s.IsCopyFromStdin()
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// lineAt возвращает номер строки для смещения offset
+func lineAt(sql string, offset int) int {
+	return strings.Count(sql[:offset], "\n") + 1
+}
+
+// StripLeadingComments удаляет пробелы и комментарии в начале запроса
+func StripLeadingComments(sql string) string {
+	for {
+		sql = strings.TrimLeft(sql, " \t\r\n\f\v")
+		switch {
+		case strings.HasPrefix(sql, "--"):
+			end := strings.IndexByte(sql, '\n')
+			if end < 0 {
+				return ""
+			}
+			sql = sql[end+1:]
+		case strings.HasPrefix(sql, "/*"):
+			end, err := skipBlockComment(sql, 0)
+			if err != nil {
+				return ""
+			}
+			sql = sql[end:]
+		default:
+			return sql
+		}
+	}
+}
+
+// skipBlockComment возвращает смещение после блочного комментария, начинающегося в i, с учетом вложенности
+func skipBlockComment(sql string, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(sql)-1; j++ {
+		switch {
+		case sql[j] == '/' && sql[j+1] == '*':
+			depth++
+			j++
+		case sql[j] == '*' && sql[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated block comment at line %d", lineAt(sql, i))
+}
+
+// skipQuoted возвращает смещение после строки или идентификатора в кавычках quote, начинающихся в i.
+// В строках E'...' обратная косая черта экранирует следующий символ.
+func skipQuoted(sql string, i int, quote byte, backslashEscapes bool) (int, error) {
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if backslashEscapes {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, nil
+		}
+	}
+
+	if quote == '"' {
+		return 0, fmt.Errorf("unterminated quoted identifier at line %d", lineAt(sql, i))
+	}
+	return 0, fmt.Errorf("unterminated quoted string at line %d", lineAt(sql, i))
+}
+
+// dollarTag возвращает тег $tag$, начинающийся в i, или пустую строку, если это не dollar quote
+func dollarTag(sql string, i int) string {
+	if i > 0 && isIdentChar(sql[i-1]) {
+		return ""
+	}
+
+	j := i + 1
+	if j < len(sql) && sql[j] != '$' {
+		if !isIdentStart(sql[j]) {
+			return ""
+		}
+		for j < len(sql) && isIdentChar(sql[j]) && sql[j] != '$' {
+			j++
+		}
+	}
+
+	if j >= len(sql) || sql[j] != '$' {
+		return ""
+	}
+
+	return sql[i : j+1]
+}
+
+// splitter состояние разбора скрипта
+type splitter struct {
+	sql        string
+	statements []Statement
+	start      int
+	hasCode    bool
+	// atomicDepth глубина BEGIN ATOMIC ... END и CASE ... END внутри тела функции
+	atomicDepth int
+}
+
+// finish завершает запрос, заканчивающийся перед end, и сообщает, был ли добавлен запрос
+func (s *splitter) finish(end int) bool {
+	added := false
+	if s.hasCode {
+		raw := s.sql[s.start:end]
+		offset := s.start + len(raw) - len(strings.TrimLeft(raw, " \t\r\n\f\v"))
+		s.statements = append(s.statements, Statement{
+			SQL:    strings.TrimSpace(raw),
+			Line:   lineAt(s.sql, offset),
+			Offset: offset,
+			End:    end,
+		})
+		added = true
+	}
+
+	s.hasCode = false
+	s.atomicDepth = 0
+
+	return added
+}
+
+// last возвращает последний добавленный запрос
+func (s *splitter) last() *Statement {
+	return &s.statements[len(s.statements)-1]
+}
+
+// atLineStart перед смещением i в строке только пробелы
+func (s *splitter) atLineStart(i int) bool {
+	for j := i - 1; j >= 0 && s.sql[j] != '\n'; j-- {
+		if !isSpace(s.sql[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// meta добавляет мета-команду psql, начинающуюся в i, и возвращает смещение конца ее строки
+func (s *splitter) meta(i int) (int, error) {
+	if s.hasCode {
+		return 0, fmt.Errorf("psql meta-command inside a statement at line %d is not supported", lineAt(s.sql, i))
+	}
+
+	end := len(s.sql)
+	if lineEnd := strings.IndexByte(s.sql[i:], '\n'); lineEnd >= 0 {
+		end = i + lineEnd
+	}
+
+	s.statements = append(s.statements, Statement{
+		SQL:    strings.TrimSpace(s.sql[i:end]),
+		Line:   lineAt(s.sql, i),
+		Meta:   true,
+		Offset: i,
+		End:    end,
+	})
+
+	return end, nil
+}
+
+// readCopyData читает данные COPY, начинающиеся со строки после i, до строки \.
+// и возвращает смещение после завершающей строки
+func (s *splitter) readCopyData(i int) (int, error) {
+	lineEnd := strings.IndexByte(s.sql[i:], '\n')
+	if lineEnd < 0 {
+		return 0, fmt.Errorf("unterminated COPY data at line %d", lineAt(s.sql, i))
+	}
+
+	dataStart := i + lineEnd + 1
+	pos := dataStart
+	for pos <= len(s.sql) {
+		end := strings.IndexByte(s.sql[pos:], '\n')
+		line := s.sql[pos:]
+		next := len(s.sql)
+		if end >= 0 {
+			line = s.sql[pos : pos+end]
+			next = pos + end + 1
+		}
+
+		if strings.TrimRight(line, "\r") == `\.` {
+			s.last().CopyData = s.sql[dataStart:pos]
+			s.last().End = next
+			return next, nil
+		}
+
+		if end < 0 {
+			break
+		}
+		pos = next
+	}
+
+	return 0, fmt.Errorf("unterminated COPY data at line %d", lineAt(s.sql, dataStart))
+}
+
+// word обрабатывает ключевое слово для отслеживания тел BEGIN ATOMIC
+func (s *splitter) word(w string) {
+	switch strings.ToLower(w) {
+	case "begin":
+		if routineRegexp.MatchString(StripLeadingComments(s.sql[s.start:])) {
+			s.atomicDepth++
+		}
+	case "case":
+		if s.atomicDepth > 0 {
+			s.atomicDepth++
+		}
+	case "end":
+		if s.atomicDepth > 0 {
+			s.atomicDepth--
+		}
+	}
+}
+
+// SplitTopLevel разбивает строку по разделителю вне скобок и кавычек
+func SplitTopLevel(s string, sep byte) []string {
+	parts := make([]string, 0)
+	depth := 0
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// Split разбивает sql скрипт на запросы. Запросы, состоящие только из комментариев, пропускаются.
+func Split(sql string) ([]Statement, error) {
+	s := &splitter{sql: sql, statements: make([]Statement, 0)}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end, err := skipBlockComment(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '\'':
+			escapes := i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i < 2 || !isIdentChar(sql[i-2]))
+			end, err := skipQuoted(sql, i, '\'', escapes)
+			if err != nil {
+				return nil, err
+			}
+			s.hasCode = true
+			i = end
+		case c == '"':
+			end, err := skipQuoted(sql, i, '"', false)
+			if err != nil {
+				return nil, err
+			}
+			s.hasCode = true
+			i = end
+		case c == '$':
+			s.hasCode = true
+			tag := dollarTag(sql, i)
+			if tag == "" {
+				i++
+				continue
+			}
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string %s at line %d", tag, lineAt(sql, i))
+			}
+			i += len(tag) + end + len(tag)
+		case c == ';' && s.atomicDepth == 0:
+			added := s.finish(i)
+			i++
+			if added {
+				s.last().End = i
+				if s.last().hasCopyData() {
+					end, err := s.readCopyData(i)
+					if err != nil {
+						return nil, err
+					}
+					i = end
+				}
+			}
+			s.start = i
+		case c == '\\' && s.atLineStart(i):
+			end, err := s.meta(i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			if s.last().hasCopyData() {
+				if i, err = s.readCopyData(i); err != nil {
+					return nil, err
+				}
+			}
+			s.start = i
+		case isIdentStart(c) && (i == 0 || !isIdentChar(sql[i-1])):
+			j := i
+			for j < len(sql) && isIdentChar(sql[j]) {
+				j++
+			}
+			s.hasCode = true
+			s.word(sql[i:j])
+			i = j
+		default:
+			if !isSpace(c) {
+				s.hasCode = true
+			}
+			i++
+		}
+	}
+
+	s.finish(len(sql))
+
+	return s.statements, nil
+}
@@ -0,0 +1,143 @@
+package sqlsplit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sqls(statements []Statement) []string {
+	result := make([]string, 0, len(statements))
+	for _, s := range statements {
+		result = append(result, s.SQL)
+	}
+	return result
+}
+
+func Test_Split(t *testing.T) {
+	t.Run("should split simple statements", func(t *testing.T) {
+		statements, err := Split("CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\nDROP TABLE c")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)", "DROP TABLE c"}, sqls(statements))
+		assert.Equal(t, 1, statements[0].Line)
+		assert.Equal(t, 3, statements[1].Line)
+		assert.Equal(t, 4, statements[2].Line)
+	})
+
+	t.Run("should skip comment only statements", func(t *testing.T) {
+		statements, err := Split("-- header;\n/* block; */\n;\nSELECT 1; -- tail")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SELECT 1"}, sqls(statements))
+	})
+
+	t.Run("should ignore semicolons in strings and identifiers", func(t *testing.T) {
+		statements, err := Split(`INSERT INTO "a;b" VALUES ('x;''y', E'\';', 'c:\');SELECT 2`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`INSERT INTO "a;b" VALUES ('x;''y', E'\';', 'c:\')`, "SELECT 2"}, sqls(statements))
+	})
+
+	t.Run("should ignore semicolons in nested block comments", func(t *testing.T) {
+		statements, err := Split("SELECT /* a; /* b; */ c; */ 1;SELECT 2")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SELECT /* a; /* b; */ c; */ 1", "SELECT 2"}, sqls(statements))
+	})
+
+	t.Run("should keep dollar quoted bodies", func(t *testing.T) {
+		sql := "CREATE FUNCTION f() RETURNS INT AS $fn$\nBEGIN\n  PERFORM $$x;$$;\n  RETURN 1;\nEND;\n$fn$ LANGUAGE plpgsql;\nSELECT f()"
+		statements, err := Split(sql)
+		require.NoError(t, err)
+		require.Len(t, statements, 2)
+		assert.Contains(t, statements[0].SQL, "RETURN 1;")
+		assert.Equal(t, 7, statements[1].Line)
+	})
+
+	t.Run("should not treat parameters and identifiers as dollar quotes", func(t *testing.T) {
+		statements, err := Split("PREPARE p AS SELECT $1, a$b$c FROM t;SELECT 2")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"PREPARE p AS SELECT $1, a$b$c FROM t", "SELECT 2"}, sqls(statements))
+	})
+
+	t.Run("should keep begin atomic bodies", func(t *testing.T) {
+		sql := "CREATE OR REPLACE FUNCTION f(a INT) RETURNS INT LANGUAGE sql\nBEGIN ATOMIC\n  SELECT CASE WHEN a > 0 THEN 1 ELSE 0 END;\n  SELECT 2;\nEND;\nBEGIN;\nSELECT 3;\nEND"
+		statements, err := Split(sql)
+		require.NoError(t, err)
+		assert.Len(t, statements, 4)
+		assert.Contains(t, statements[0].SQL, "SELECT 2;\nEND")
+		assert.Equal(t, []string{"BEGIN", "SELECT 3", "END"}, sqls(statements[1:]))
+	})
+
+	t.Run("should read copy data", func(t *testing.T) {
+		sql := "COPY users (id, name) FROM STDIN;\n1\ta;b\n2\tb\n\\.\nSELECT 1;"
+		statements, err := Split(sql)
+		require.NoError(t, err)
+		require.Len(t, statements, 2)
+		assert.True(t, statements[0].IsCopyFromStdin())
+		assert.Equal(t, "1\ta;b\n2\tb\n", statements[0].CopyData)
+		assert.Equal(t, "SELECT 1", statements[1].SQL)
+		assert.Equal(t, 5, statements[1].Line)
+		assert.False(t, statements[1].IsCopyFromStdin())
+	})
+
+	t.Run("should fail on unterminated constructs", func(t *testing.T) {
+		for _, sql := range []string{
+			"SELECT 'abc",
+			`SELECT "abc`,
+			"SELECT /* abc",
+			"SELECT $a$ abc $b$",
+			"COPY t FROM STDIN;\n1\n",
+		} {
+			_, err := Split(sql)
+			assert.Error(t, err, sql)
+		}
+	})
+}
+
+func Test_SplitMeta(t *testing.T) {
+	t.Run("should return psql meta-commands", func(t *testing.T) {
+		sql := "\\set ON_ERROR_STOP on\nCREATE TABLE a (id INT);\n  \\ir shared/b.sql\nSELECT 1;"
+		statements, err := Split(sql)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`\set ON_ERROR_STOP on`, "CREATE TABLE a (id INT)", `\ir shared/b.sql`, "SELECT 1"}, sqls(statements))
+		assert.True(t, statements[0].Meta)
+		assert.False(t, statements[1].Meta)
+		assert.Equal(t, 3, statements[2].Line)
+		for _, s := range statements {
+			assert.Equal(t, s.SQL, sql[s.Offset:s.Offset+len(s.SQL)])
+		}
+		assert.Equal(t, "CREATE TABLE a (id INT);", sql[statements[1].Offset:statements[1].End])
+		assert.Equal(t, `\ir shared/b.sql`, sql[statements[2].Offset:statements[2].End])
+	})
+
+	t.Run("should read copy data of meta copy", func(t *testing.T) {
+		statements, err := Split("\\copy users FROM stdin\n\\N\ta\n\\.\nSELECT 1;")
+		require.NoError(t, err)
+		require.Len(t, statements, 2)
+		assert.True(t, statements[0].Meta)
+		assert.False(t, statements[0].IsCopyFromStdin())
+		assert.Equal(t, "\\N\ta\n", statements[0].CopyData)
+		assert.Equal(t, "SELECT 1", statements[1].SQL)
+	})
+
+	t.Run("should fail on meta-command inside statement", func(t *testing.T) {
+		_, err := Split("SELECT\n\\i a.sql\n1;")
+		assert.EqualError(t, err, "psql meta-command inside a statement at line 2 is not supported")
+	})
+}
+
+func Test_Interpolate(t *testing.T) {
+	vars := map[string]string{"schema": "app", "name": "O'Brien", "col": `a"b`}
+
+	t.Run("should interpolate variables", func(t *testing.T) {
+		sql := "SELECT :'name', :\"col\" FROM :schema.users WHERE id::text = ':schema' -- :schema\n AND x = $$:schema$$ AND y = :unknown"
+		assert.Equal(
+			t,
+			"SELECT 'O''Brien', \"a\"\"b\" FROM app.users WHERE id::text = ':schema' -- :schema\n AND x = $$:schema$$ AND y = :unknown",
+			Interpolate(sql, vars),
+		)
+	})
+
+	t.Run("should keep sql without variables", func(t *testing.T) {
+		assert.Equal(t, "SELECT :a", Interpolate("SELECT :a", nil))
+	})
+}
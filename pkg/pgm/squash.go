@@ -0,0 +1,45 @@
+package pgm
+
+// maxSquashExpansions ограничение количества вариантов истории одной объединенной миграции
+const maxSquashExpansions = 16
+
+// SquashedMigration миграция, объединенная в базовую миграцию командой squash
+type SquashedMigration struct {
+	Name string `json:"name"`
+	// Expansions последовательности миграций, которые могли быть применены вместо Name,
+	// если Name сама была базовой миграцией
+	Expansions [][]string `json:"expansions,omitempty"`
+}
+
+// Alternatives возвращает последовательности имен, которыми миграция могла быть записана в таблицу миграций:
+// сама миграция и варианты ее раскрытия
+func (s SquashedMigration) Alternatives() [][]string {
+	return append([][]string{{s.Name}}, s.Expansions...)
+}
+
+// Squash возвращает запись об объединении миграции migration в новую базовую миграцию.
+// Если migration сама является базовой, варианты раскрытия сохраняются, чтобы базы данных,
+// применившие как ее, так и объединенные в нее миграции, считались находящимися на новой базовой миграции.
+func Squash(migration Migration) SquashedMigration {
+	squashed := SquashedMigration{Name: migration.Name}
+	if len(migration.Squashed) == 0 {
+		return squashed
+	}
+
+	expansions := [][]string{{}}
+	for _, s := range migration.Squashed {
+		next := make([][]string, 0)
+		for _, prefix := range expansions {
+			for _, alternative := range s.Alternatives() {
+				if len(next) == maxSquashExpansions {
+					break
+				}
+				next = append(next, append(append(make([]string, 0, len(prefix)+len(alternative)), prefix...), alternative...))
+			}
+		}
+		expansions = next
+	}
+
+	squashed.Expansions = expansions
+	return squashed
+}
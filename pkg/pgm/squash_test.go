@@ -0,0 +1,24 @@
+package pgm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Squash(t *testing.T) {
+	assert.Equal(t, SquashedMigration{Name: "1_a"}, Squash(Migration{Name: "1_a"}))
+
+	baseline := Migration{
+		Name: "2_baseline",
+		Squashed: []SquashedMigration{
+			{Name: "1_a"},
+			{Name: "2_b", Expansions: [][]string{{"1_x", "2_y"}}},
+		},
+	}
+
+	squashed := Squash(baseline)
+	assert.Equal(t, "2_baseline", squashed.Name)
+	assert.Equal(t, [][]string{{"1_a", "2_b"}, {"1_a", "1_x", "2_y"}}, squashed.Expansions)
+	assert.Equal(t, [][]string{{"2_baseline"}, {"1_a", "2_b"}, {"1_a", "1_x", "2_y"}}, squashed.Alternatives())
+}
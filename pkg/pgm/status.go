@@ -0,0 +1,42 @@
+package pgm
+
+import (
+	"time"
+)
+
+type MigrationState string
+
+const (
+	// STATE_APPLIED миграция есть в файловой системе и применена к базе
+	STATE_APPLIED MigrationState = "applied"
+	// STATE_PENDING миграция есть в файловой системе, но еще не применена
+	STATE_PENDING MigrationState = "pending"
+	// STATE_MISSING миграция применена к базе, но отсутствует в файловой системе
+	STATE_MISSING MigrationState = "missing"
+)
+
+// MigrationStatus состояние миграции относительно базы данных
+type MigrationStatus struct {
+	Name      string         `json:"name"`
+	State     MigrationState `json:"state"`
+	AppliedAt *time.Time     `json:"appliedAt,omitempty"`
+	// Checksum контрольная сумма up sql из файловой системы
+	Checksum string `json:"checksum,omitempty"`
+	// AppliedChecksum контрольная сумма up sql на момент применения
+	AppliedChecksum string `json:"appliedChecksum,omitempty"`
+	// Modified файл миграции изменился после применения
+	Modified bool `json:"modified"`
+}
+
+type PlanAction string
+
+const (
+	APPLY  PlanAction = "apply"
+	REVERT PlanAction = "revert"
+)
+
+// PlanStep шаг плана миграции
+type PlanStep struct {
+	Name   string     `json:"name"`
+	Action PlanAction `json:"action"`
+}
@@ -0,0 +1,82 @@
+package pgm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrorPolicy поведение при ошибке миграции одной из целей (тенанта или базы данных)
+type ErrorPolicy string
+
+const (
+	// STOP не запускать новые цели после первой ошибки
+	STOP ErrorPolicy = "stop"
+	// CONTINUE продолжать миграцию остальных целей
+	CONTINUE ErrorPolicy = "continue"
+	// FAIL_FAST после первой ошибки не запускать новые цели и прервать уже запущенные
+	FAIL_FAST ErrorPolicy = "failFast"
+)
+
+// TargetStatus итог миграции одной цели
+type TargetStatus string
+
+const (
+	TARGET_SUCCEEDED TargetStatus = "succeeded"
+	TARGET_FAILED    TargetStatus = "failed"
+	TARGET_SKIPPED   TargetStatus = "skipped"
+)
+
+// TargetResult результат миграции одного тенанта или базы данных
+type TargetResult struct {
+	Target   string
+	Status   TargetStatus
+	Results  []MigrationResult
+	Duration time.Duration
+	Err      error
+}
+
+// TargetsError миграция части целей завершилась ошибкой или не была запущена
+type TargetsError struct {
+	Failed  []string
+	Skipped []string
+	Total   int
+	// Errs ошибки упавших целей в порядке Failed
+	Errs []error
+}
+
+func (e *TargetsError) Error() string {
+	msg := fmt.Sprintf("%d of %d targets failed", len(e.Failed), e.Total)
+	if len(e.Failed) > 0 {
+		msg += ": " + strings.Join(e.Failed, ", ")
+	}
+
+	return fmt.Sprintf("%s, %d skipped", msg, len(e.Skipped))
+}
+
+func (e *TargetsError) Unwrap() []error {
+	return e.Errs
+}
+
+// CheckTargets возвращает TargetsError, если хотя бы одна цель не была успешно мигрирована
+func CheckTargets(results []TargetResult) error {
+	failed, skipped := make([]string, 0), make([]string, 0)
+	errs := make([]error, 0)
+	for _, r := range results {
+		switch r.Status {
+		case TARGET_FAILED:
+			failed = append(failed, r.Target)
+			if r.Err != nil {
+				errs = append(errs, r.Err)
+			}
+		case TARGET_SKIPPED:
+			skipped = append(skipped, r.Target)
+		}
+	}
+
+	if len(failed) == 0 && len(skipped) == 0 {
+		return nil
+	}
+
+	return &TargetsError{Failed: failed, Skipped: skipped, Total: len(results), Errs: errs}
+}
@@ -0,0 +1,96 @@
+package pgm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// VarEnvPrefix префикс переменных окружения со значениями плейсхолдеров
+const VarEnvPrefix = "PGM_VAR_"
+
+var varNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ParseVar разбирает значение флага --var в формате key=value
+func ParseVar(s string) (string, string, error) {
+	key, value, found := strings.Cut(s, "=")
+	if !found {
+		return "", "", fmt.Errorf("invalid var \"%s\", expected key=value", s)
+	}
+
+	if !varNameRegexp.MatchString(key) {
+		return "", "", fmt.Errorf("invalid var name \"%s\", might contain only letters, numbers and \"_\" symbol", key)
+	}
+
+	return key, value, nil
+}
+
+// VarsFromEnv собирает значения плейсхолдеров из переменных окружения PGM_VAR_<name>
+func VarsFromEnv(environ []string) map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		key, value, found := strings.Cut(kv, "=")
+		if !found {
+			continue
+		}
+
+		name, found := strings.CutPrefix(key, VarEnvPrefix)
+		if !found || !varNameRegexp.MatchString(name) {
+			continue
+		}
+
+		vars[name] = value
+	}
+
+	return vars
+}
+
+// Substitute заменяет плейсхолдеры ${name} в sql значениями из vars.
+// Последовательность $${ оставляет в sql литерал ${.
+// Если для плейсхолдера нет значения, возвращается UnresolvedPlaceholderError.
+func Substitute(sql string, vars map[string]string) (string, error) {
+	if !strings.Contains(sql, "${") {
+		return sql, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(sql))
+	unresolved := make([]string, 0)
+	seen := make(map[string]struct{})
+
+	for i := 0; i < len(sql); {
+		if strings.HasPrefix(sql[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+
+		if !strings.HasPrefix(sql[i:], "${") {
+			b.WriteByte(sql[i])
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(sql[i+2:], '}')
+		if end < 0 || !varNameRegexp.MatchString(sql[i+2:i+2+end]) {
+			b.WriteString("${")
+			i += 2
+			continue
+		}
+
+		name := sql[i+2 : i+2+end]
+		if value, found := vars[name]; found {
+			b.WriteString(value)
+		} else if _, found = seen[name]; !found {
+			seen[name] = struct{}{}
+			unresolved = append(unresolved, name)
+		}
+		i += 2 + end + 1
+	}
+
+	if len(unresolved) > 0 {
+		return "", &UnresolvedPlaceholderError{Names: unresolved}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,61 @@
+package pgm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Substitute(t *testing.T) {
+	vars := map[string]string{"schema": "billing", "role": "app_rw"}
+
+	t.Run("should replace placeholders", func(t *testing.T) {
+		sql, err := Substitute("CREATE SCHEMA ${schema}; GRANT USAGE ON SCHEMA ${schema} TO ${role};", vars)
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE SCHEMA billing; GRANT USAGE ON SCHEMA billing TO app_rw;", sql)
+	})
+
+	t.Run("should keep escaped placeholder", func(t *testing.T) {
+		sql, err := Substitute("SELECT '$${schema}', '${schema}';", vars)
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT '${schema}', 'billing';", sql)
+	})
+
+	t.Run("should keep sql without placeholders", func(t *testing.T) {
+		sql, err := Substitute("SELECT $1, $$body$$, '${ not a name }';", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT $1, $$body$$, '${ not a name }';", sql)
+	})
+
+	t.Run("should return error on unresolved placeholders", func(t *testing.T) {
+		sql, err := Substitute("CREATE TABLE ${schema}.t (); ALTER TABLE ${schema}.t OWNER TO ${owner}; SET ${tbs};", vars)
+		assert.Equal(t, "", sql)
+		assert.EqualError(t, err, "unresolved placeholders: owner, tbs")
+		assert.True(t, errors.Is(err, ErrUnresolvedPlaceholder))
+	})
+}
+
+func Test_ParseVar(t *testing.T) {
+	t.Run("should parse key and value", func(t *testing.T) {
+		key, value, err := ParseVar("schema=a=b")
+		assert.Nil(t, err)
+		assert.Equal(t, "schema", key)
+		assert.Equal(t, "a=b", value)
+	})
+
+	t.Run("should return error without value", func(t *testing.T) {
+		_, _, err := ParseVar("schema")
+		assert.EqualError(t, err, "invalid var \"schema\", expected key=value")
+	})
+
+	t.Run("should return error on invalid name", func(t *testing.T) {
+		_, _, err := ParseVar("my-schema=a")
+		assert.ErrorContains(t, err, "invalid var name \"my-schema\"")
+	})
+}
+
+func Test_VarsFromEnv(t *testing.T) {
+	vars := VarsFromEnv([]string{"PGM_VAR_schema=billing", "PGM_VAR_=x", "HOME=/root", "PGM_VAR_role=a=b"})
+	assert.Equal(t, map[string]string{"schema": "billing", "role": "a=b"}, vars)
+}